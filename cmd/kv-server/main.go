@@ -1,42 +1,404 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
 	"github.com/MosinFAM/tarantool-kv/internal/db"
 	"github.com/MosinFAM/tarantool-kv/internal/handlers"
+	"github.com/MosinFAM/tarantool-kv/internal/metrics"
+	"github.com/MosinFAM/tarantool-kv/internal/tracing"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
 
 	"github.com/MosinFAM/tarantool-kv/internal/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout ограничивает ожидание текущих запросов и выгрузки
+// спанов при остановке сервера
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Инициализация логирования
-	logger.Init()
+	logOptions, err := loadLogOptions()
+	if err != nil {
+		logger.Init()
+		logger.LogError("Invalid logging configuration", err, nil)
+		os.Exit(1)
+	}
+	logger.Init(logOptions...)
+	// kill -USR1 переключает уровень логов на debug и обратно
+	logger.ToggleDebugOnSignal(context.Background(), syscall.SIGUSR1)
 
-	conn, err := db.ConnectTarantool()
+	namespaces, err := newNamespaces(os.Getenv("STORAGE_BACKEND"))
 	if err != nil {
-		logger.LogError("Failed to connect to Tarantool", err, logrus.Fields{
-			"host": os.Getenv("TARANTOOL_HOST"),
-			"port": os.Getenv("TARANTOOL_PORT"),
+		logger.LogError("Failed to initialize storage", err, logrus.Fields{
+			"backend": os.Getenv("STORAGE_BACKEND"),
+			"host":    os.Getenv("TARANTOOL_HOST"),
+			"port":    os.Getenv("TARANTOOL_PORT"),
 		})
 		os.Exit(1)
 	}
 
-	kvManager := db.NewKeyValueManager(conn)
-	handler := handlers.NewHandler(kvManager)
+	timeouts, err := loadTimeouts()
+	if err != nil {
+		logger.LogError("Invalid storage timeout", err, nil)
+		os.Exit(1)
+	}
 
-	r := gin.Default()
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter: os.Getenv("OTEL_TRACES_EXPORTER"),
+		File:     os.Getenv("OTEL_TRACES_FILE"),
+	})
+	if err != nil {
+		logger.LogError("Failed to initialize tracing", err, nil)
+		os.Exit(1)
+	}
 
-	r.POST("/kv", handler.CreateKeyValue)
-	r.PUT("/kv/:id", handler.UpdateKeyValue)
-	r.GET("/kv/:id", handler.GetKeyValue)
-	r.DELETE("/kv/:id", handler.DeleteKeyValue)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(registry)
 
-	if err := r.Run(":8080"); err != nil {
-		logger.LogError("Failed to start server", err, nil)
+	// Подписчики watch получают изменения из журнала своего пространства
+	// имен. Журнал читается мимо метрик и спанов, чтобы его опрос не
+	// смешивался с запросами клиентов.
+	instrumented := metrics.NewNamespaces(tracing.NewNamespaces(namespaces), m)
+	watched := watch.NewNamespaces(instrumented, watch.DefaultBuffer, watch.WithSource(namespaces))
+	storage, err := watched.Namespace(context.Background(), db.DefaultNamespace)
+	if err != nil {
+		logger.LogError("Failed to open default namespace", err, nil)
 		os.Exit(1)
 	}
+
+	authenticator, err := newAuthenticator()
+	if err != nil {
+		logger.LogError("Failed to initialize authentication", err, nil)
+		os.Exit(1)
+	}
+
+	policy, err := loadPolicy()
+	if err != nil {
+		logger.LogError("Failed to load access policy", err, nil)
+		os.Exit(1)
+	}
+
+	handler := handlers.NewHandler(storage,
+		handlers.WithTimeouts(timeouts),
+		handlers.WithBroadcaster(watched.Broadcaster(db.DefaultNamespace)),
+		handlers.WithNamespaces(watched),
+		handlers.WithBroadcasters(watched),
+		handlers.WithPolicy(policy),
+	)
+
+	// Вместо логгера gin запросы записывает logger.Middleware,
+	// чтобы строки запросов были в том же формате и с request_id
+	r := gin.New()
+	r.Use(logger.Middleware(), gin.Recovery(), tracing.Middleware(), m.Middleware())
+
+	// /metrics отдается без аутентификации, чтобы его мог опрашивать Prometheus
+	r.GET("/metrics", gin.WrapH(metrics.Handler(registry)))
+
+	api := r.Group("")
+	if authenticator != nil {
+		api.Use(auth.Middleware(authenticator))
+	} else {
+		logger.LogInfo("Authentication is disabled, set AUTH_JWT_*, AUTH_API_KEYS_FILE or AUTH_PROXY_HEADER to enable it", nil)
+	}
+
+	api.GET("/admin/loglevel", handler.GetLogLevel)
+	api.PUT("/admin/loglevel", handler.SetLogLevel)
+
+	api.POST("/ns", handler.CreateNamespace)
+	api.GET("/ns", handler.ListNamespaces)
+	api.DELETE("/ns/:ns", handler.DeleteNamespace)
+
+	// Маршруты /kv работают с пространством имен default,
+	// /ns/:ns/kv - с пространством из пути
+	registerKeyRoutes(api.Group("/kv"), handler)
+	registerKeyRoutes(api.Group("/ns/:ns/kv", handler.ResolveNamespace), handler)
+
+	// SIGTERM и SIGINT останавливают прием новых соединений, после чего
+	// сервер ждет завершения текущих запросов и выгружает накопленные спаны
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	server := &http.Server{Addr: ":8080", Handler: r}
+	// Потоки watch не завершаются сами, поэтому при остановке их подписки
+	// закрываются, и Shutdown дожидается только обычных запросов
+	server.RegisterOnShutdown(watched.Shutdown)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		logger.LogError("Failed to start server", err, nil)
+		exitCode = 1
+	case <-ctx.Done():
+		stop()
+		logger.LogInfo("Shutting down server", nil)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.LogError("Failed to shut down server gracefully", err, nil)
+			exitCode = 1
+		}
+		cancel()
+	}
+
+	tracingCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.LogError("Failed to flush traces", err, nil)
+		exitCode = 1
+	}
+	if exitCode != 0 {
+		cancel()
+		os.Exit(exitCode)
+	}
+}
+
+// registerKeyRoutes регистрирует маршруты ключей в группе /kv или /ns/:ns/kv
+func registerKeyRoutes(g *gin.RouterGroup, handler *handlers.Handler) {
+	g.POST("", handler.CreateKeyValue)
+	g.GET("", handler.ListKeyValues)
+	g.POST("/_batch", handler.BatchKeyValues)
+	g.GET("/_watch", handler.WatchKeyValues)
+	g.GET("/_changes", handler.ListChanges)
+	g.GET("/_trash", handler.ListTrash)
+	g.POST("/_trash/:id/restore", handler.RestoreTrashKeyValue)
+	g.PUT("/:id", handler.UpdateKeyValue)
+	g.PATCH("/:id", handler.PatchKeyValue)
+	g.GET("/:id", handler.GetKeyValue)
+	g.GET("/:id/history", handler.GetKeyValueHistory)
+	g.POST("/:id/restore", handler.RestoreKeyValue)
+	g.DELETE("/:id", handler.DeleteKeyValue)
+}
+
+// newNamespaces выбирает реализацию хранилища по значению STORAGE_BACKEND.
+// По умолчанию используется Tarantool.
+func newNamespaces(backend string) (db.Namespaces, error) {
+	switch backend {
+	case "", "tarantool":
+		conn, err := db.ConnectTarantool()
+		if err != nil {
+			return nil, err
+		}
+		return db.NewKeyValueManager(conn), nil
+	case "memory":
+		logger.LogInfo("Using in-memory storage", nil)
+		historySize, err := intFromEnv("KV_HISTORY_SIZE", db.DefaultHistorySize)
+		if err != nil {
+			return nil, err
+		}
+		trashRetention, err := intFromEnv("KV_TRASH_RETENTION", 0)
+		if err != nil {
+			return nil, err
+		}
+		expireInterval, err := intFromEnv("KV_EXPIRE_INTERVAL", 1)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewMemoryNamespaces(
+			db.WithHistorySize(historySize),
+			db.WithTrashRetention(time.Duration(trashRetention)*time.Second),
+			db.WithExpireInterval(time.Duration(expireInterval)*time.Second),
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// newAuthenticator собирает проверки клиентов из окружения:
+// JWT по ключам из AUTH_JWT_* (см. newJWT), API-ключи из файла
+// AUTH_API_KEYS_FILE и имя клиента из заголовка AUTH_PROXY_HEADER, которому
+// сервер доверяет только от прокси из сетей AUTH_TRUSTED_PROXIES (CIDR через
+// запятую). Без них аутентификация выключена и возвращается nil.
+func newAuthenticator() (auth.Authenticator, error) {
+	var authenticators []auth.Authenticator
+
+	// JWT проверяется первым: токены без трех частей он пропускает
+	// дальше, а APIKeys отклонил бы любой неизвестный bearer-токен
+	jwt, err := newJWT()
+	if err != nil {
+		return nil, err
+	}
+	if jwt != nil {
+		authenticators = append(authenticators, jwt)
+	}
+
+	if path := os.Getenv("AUTH_API_KEYS_FILE"); path != "" {
+		keys, err := auth.LoadAPIKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, keys)
+	}
+
+	if header := os.Getenv("AUTH_PROXY_HEADER"); header != "" {
+		proxy, err := auth.NewProxyHeader(header, listFromEnv("AUTH_TRUSTED_PROXIES"))
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, proxy)
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+	return auth.Chain(authenticators...), nil
+}
+
+// newJWT загружает ключи проверки JWT: секрет HS256 из AUTH_JWT_HMAC_KEY_FILE,
+// открытые ключи RS256/ES256 в PEM из AUTH_JWT_PUBLIC_KEYS (файлы через
+// запятую) и набор ключей из AUTH_JWT_JWKS_FILE. AUTH_JWT_AUDIENCE задает
+// обязательное значение claim "aud". Без ключей возвращается nil.
+func newJWT() (*auth.JWT, error) {
+	var keys []auth.JWTKey
+
+	if path := os.Getenv("AUTH_JWT_HMAC_KEY_FILE"); path != "" {
+		key, err := auth.LoadHMACKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	for _, path := range listFromEnv("AUTH_JWT_PUBLIC_KEYS") {
+		key, err := auth.LoadPublicKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if path := os.Getenv("AUTH_JWT_JWKS_FILE"); path != "" {
+		set, err := auth.LoadJWKSFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, set...)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return auth.NewJWT(keys, auth.WithAudience(os.Getenv("AUTH_JWT_AUDIENCE")))
+}
+
+// loadPolicy загружает политику доступа из файла AUTH_POLICY_FILE.
+// Без него права не проверяются и возвращается nil.
+func loadPolicy() (*auth.Policy, error) {
+	path := os.Getenv("AUTH_POLICY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return auth.LoadPolicy(path)
+}
+
+// loadLogOptions читает настройки логирования из окружения: LOG_FORMAT
+// (text или json), LOG_LEVEL (debug, info, warn, error) и LOG_OUTPUT
+// (stdout, stderr или путь файла)
+func loadLogOptions() ([]logger.Option, error) {
+	format, err := logger.ParseFormat(os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		return nil, err
+	}
+	opts := []logger.Option{logger.WithFormat(format)}
+
+	if raw := os.Getenv("LOG_LEVEL"); raw != "" {
+		level, err := logrus.ParseLevel(raw)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		opts = append(opts, logger.WithLevel(level))
+	}
+
+	output, err := logger.OpenOutput(os.Getenv("LOG_OUTPUT"))
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, logger.WithOutput(output)), nil
+}
+
+// loadTimeouts читает дедлайны операций из окружения.
+// STORAGE_TIMEOUT задает общий дедлайн, STORAGE_TIMEOUT_<OP> - дедлайн отдельной операции.
+func loadTimeouts() (handlers.Timeouts, error) {
+	timeouts := handlers.DefaultTimeouts()
+
+	common, err := durationFromEnv("STORAGE_TIMEOUT", 0)
+	if err != nil {
+		return timeouts, err
+	}
+	if common > 0 {
+		timeouts = handlers.Timeouts{Create: common, Get: common, Update: common, Delete: common, List: common, Batch: common}
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"STORAGE_TIMEOUT_CREATE": &timeouts.Create,
+		"STORAGE_TIMEOUT_GET":    &timeouts.Get,
+		"STORAGE_TIMEOUT_UPDATE": &timeouts.Update,
+		"STORAGE_TIMEOUT_DELETE": &timeouts.Delete,
+		"STORAGE_TIMEOUT_LIST":   &timeouts.List,
+		"STORAGE_TIMEOUT_BATCH":  &timeouts.Batch,
+	} {
+		if *target, err = durationFromEnv(name, *target); err != nil {
+			return timeouts, err
+		}
+	}
+
+	return timeouts, nil
+}
+
+// intFromEnv читает неотрицательное целое число из переменной окружения.
+// Переменные, общие с init.lua, задаются числом, как и в Tarantool.
+func intFromEnv(name string, fallback int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return fallback, fmt.Errorf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// listFromEnv читает список через запятую из переменной окружения.
+// Пустые элементы отбрасываются, так что пустая переменная дает пустой список.
+func listFromEnv(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return fallback, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
 }
@@ -2,28 +2,420 @@ box.cfg{
     listen = 3301
 }
 
+local fiber = require('fiber')
+local json = require('json')
+local log = require('log')
+
+-- Формат кортежа space kv
+local kv_format = {
+    {name = 'key', type = 'string'},
+    -- Значение хранится как msgpack map и доступно Tarantool без декодирования
+    {name = 'value', type = 'map'},
+    -- Версия увеличивается при каждой записи ключа.
+    -- У кортежей, записанных до появления версий, поле отсутствует.
+    {name = 'version', type = 'unsigned', is_nullable = true},
+    -- Время истечения в секундах Unix, отсутствует у бессрочных ключей
+    {name = 'expires_at', type = 'unsigned', is_nullable = true},
+    -- Время создания и последнего изменения в секундах Unix,
+    -- отсутствуют у кортежей, записанных до появления этих полей
+    {name = 'created_at', type = 'unsigned', is_nullable = true},
+    {name = 'updated_at', type = 'unsigned', is_nullable = true},
+    -- Произвольные метки ключа {string: string}
+    {name = 'labels', type = 'map', is_nullable = true}
+}
+
 -- Создание пространства и индекса
 if not box.space.kv then
-    box.schema.space.create('kv', {
+    box.schema.space.create('kv', {format = kv_format})
+    box.space.kv:create_index('primary', {type = 'hash', parts = {'key'}})
+end
+
+-- Раньше value хранилось как JSON-строка. Пока строки не преобразованы,
+-- space использует формат с value типа any, который допускает оба вида.
+local migrate_values = box.space.kv:format()[2].type ~= 'map'
+local legacy_format = table.deepcopy(kv_format)
+legacy_format[2].type = 'any'
+
+-- Обновление формата уже существующего space
+box.space.kv:format(migrate_values and legacy_format or kv_format)
+
+-- Вторичные индексы space ключей
+local function create_kv_indexes(space)
+    -- Упорядоченный индекс для листинга ключей по префиксу.
+    -- Первичный индекс HASH не поддерживает итерацию по диапазону.
+    space:create_index('ordered', {type = 'tree', parts = {'key'}, if_not_exists = true})
+
+    -- Индекс по времени истечения для фонового удаления истекших ключей
+    space:create_index('expires', {
+        type = 'tree',
+        unique = false,
+        parts = {{field = 'expires_at', type = 'unsigned', is_nullable = true}},
+        if_not_exists = true
+    })
+end
+create_kv_indexes(box.space.kv)
+
+-- Миграция JSON-строк в msgpack-значения.
+-- Кортежи преобразуются пачками по упорядоченному индексу, каждая пачка
+-- в своей транзакции, поэтому прерванную миграцию можно повторить.
+if migrate_values then
+    local migrated = 0
+    local last = ''
+    while true do
+        local batch = box.space.kv.index.ordered:select(last, {iterator = 'GT', limit = 1000})
+        if #batch == 0 then
+            break
+        end
+        box.begin()
+        for _, tuple in ipairs(batch) do
+            if type(tuple.value) == 'string' then
+                box.space.kv:update(tuple.key, {{'=', 'value', json.decode(tuple.value)}})
+                migrated = migrated + 1
+            end
+        end
+        box.commit()
+        last = batch[#batch].key
+    end
+    box.space.kv:format(kv_format)
+    log.info('kv: migrated %d values from JSON strings to msgpack', migrated)
+end
+
+-- Журнал изменений: каждая запись в space ключей добавляет в kv_changelog
+-- строку с глобальной ревизией. Ревизии выдает последовательность, поэтому
+-- они возрастают, но после отката транзакции в них могут быть пропуски.
+local kv_changelog_format = {
+    {name = 'revision', type = 'unsigned'},
+    {name = 'type', type = 'string'},
+    {name = 'key', type = 'string'},
+    {name = 'version', type = 'unsigned', is_nullable = true},
+    -- Для delete - удаленное значение
+    {name = 'value', type = 'any', is_nullable = true},
+    {name = 'timestamp', type = 'unsigned'},
+    -- Пространство имен ключа, отсутствует у записей, сделанных до
+    -- появления пространств имен: они относятся к default
+    {name = 'namespace', type = 'string', is_nullable = true}
+}
+box.schema.space.create('kv_changelog', {if_not_exists = true, format = kv_changelog_format})
+box.space.kv_changelog:format(kv_changelog_format)
+box.space.kv_changelog:create_index('primary', {type = 'tree', parts = {'revision'}, if_not_exists = true})
+-- Индекс для чтения журнала одного пространства имен без просмотра
+-- записей остальных
+box.space.kv_changelog:create_index('namespace', {
+    type = 'tree',
+    parts = {
+        {field = 'namespace', type = 'string', is_nullable = true},
+        {field = 'revision', type = 'unsigned'}
+    },
+    if_not_exists = true
+})
+box.schema.sequence.create('kv_revision', {if_not_exists = true})
+
+-- История версий ключей: последние KV_HISTORY_SIZE версий каждого ключа
+-- (по умолчанию 10, 0 отключает историю), включая текущую
+local function create_history_space(name)
+    box.schema.space.create(name, {
+        if_not_exists = true,
         format = {
             {name = 'key', type = 'string'},
-            {name = 'value', type = 'string'}
+            {name = 'version', type = 'unsigned'},
+            {name = 'value', type = 'map'},
+            {name = 'timestamp', type = 'unsigned'}
         }
     })
-    box.space.kv:create_index('primary', {type = 'hash', parts = {'key'}})
+    box.space[name]:create_index('primary', {type = 'tree', parts = {'key', 'version'}, if_not_exists = true})
+end
+create_history_space('kv_history')
+local history_size = tonumber(os.getenv('KV_HISTORY_SIZE')) or 10
+
+-- Корзина мягко удаленных ключей. Мягкое удаление включается переменной
+-- KV_TRASH_RETENTION - сроком хранения ключей в корзине в секундах,
+-- 0 (по умолчанию) означает безвозвратное удаление.
+local kv_trash_format = {
+    {name = 'key', type = 'string'},
+    {name = 'value', type = 'map'},
+    {name = 'version', type = 'unsigned'},
+    {name = 'deleted_at', type = 'unsigned'},
+    {name = 'labels', type = 'map', is_nullable = true},
+    {name = 'created_at', type = 'unsigned', is_nullable = true}
+}
+local function create_trash_space(name)
+    box.schema.space.create(name, {if_not_exists = true, format = kv_trash_format})
+    -- Обновление формата корзины, созданной до появления меток
+    box.space[name]:format(kv_trash_format)
+    box.space[name]:create_index('primary', {type = 'tree', parts = {'key'}, if_not_exists = true})
+    box.space[name]:create_index('deleted_at', {
+        type = 'tree',
+        unique = false,
+        parts = {'deleted_at'},
+        if_not_exists = true
+    })
+end
+create_trash_space('kv_trash')
+local trash_retention = tonumber(os.getenv('KV_TRASH_RETENTION')) or 0
+
+-- Реестр пространств имен. Ключи пространства default лежат в space kv,
+-- kv_history и kv_trash, ключи остальных - в собственных space, см. space_names.
+local DEFAULT_NAMESPACE = 'default'
+box.schema.space.create('kv_namespaces', {
+    if_not_exists = true,
+    format = {
+        {name = 'name', type = 'string'},
+        {name = 'created_at', type = 'unsigned'}
+    }
+})
+box.space.kv_namespaces:create_index('primary', {type = 'tree', parts = {'name'}, if_not_exists = true})
+if box.space.kv_namespaces:get(DEFAULT_NAMESPACE) == nil then
+    box.space.kv_namespaces:insert{DEFAULT_NAMESPACE, math.floor(fiber.time())}
+end
+
+-- Записи журнала, сделанные до появления пространств имен, относятся
+-- к default. Они переносятся в него пачками, чтобы индекс namespace
+-- находил их вместе с остальными записями default.
+while true do
+    local batch = box.space.kv_changelog.index.namespace:select({box.NULL}, {limit = 1000})
+    if #batch == 0 then
+        break
+    end
+    box.begin()
+    for _, entry in ipairs(batch) do
+        box.space.kv_changelog:update(entry.revision, {{'=', 'namespace', DEFAULT_NAMESPACE}})
+    end
+    box.commit()
+end
+
+-- Служебные значения, например ревизия последней удаленной компакцией записи журнала
+box.schema.space.create('kv_meta', {
+    if_not_exists = true,
+    format = {
+        {name = 'name', type = 'string'},
+        {name = 'value', type = 'any'}
+    }
+})
+box.space.kv_meta:create_index('primary', {type = 'hash', parts = {'name'}, if_not_exists = true})
+
+-- Ревизия последней удаленной компакцией записи журнала пространства
+-- имен ns хранится в kv_meta под именем changelog_compacted/<ns>, а общая
+-- для всего журнала - под именем changelog_compacted.
+local function compacted_meta_name(ns)
+    return 'changelog_compacted/' .. ns
+end
+
+-- Раньше компакция хранила только общую ревизию. Компакция удаляет самые
+-- старые записи журнала, поэтому удаленные записи пространства старше
+-- всех оставшихся, и его ревизия не больше ревизии перед первой
+-- оставшейся записью.
+if box.space.kv_meta:get('changelog_compacted_by_namespace') == nil then
+    local legacy = box.space.kv_meta:get('changelog_compacted')
+    box.begin()
+    if legacy ~= nil then
+        for _, entry in box.space.kv_namespaces:pairs() do
+            local value = legacy.value
+            local first = box.space.kv_changelog.index.namespace:select({entry.name}, {limit = 1})[1]
+            if first ~= nil and first.revision - 1 < value then
+                value = first.revision - 1
+            end
+            box.space.kv_meta:replace{compacted_meta_name(entry.name), value}
+        end
+    end
+    box.space.kv_meta:replace{'changelog_compacted_by_namespace', true}
+    box.commit()
+end
+
+-- Код ошибки несовпадения версии, см. tntErrVersionMismatch в internal/db
+local ERR_VERSION_MISMATCH = 10001
+-- Код ошибки обращения к несуществующему пространству имен,
+-- см. tntErrNamespaceNotFound в internal/db
+local ERR_NAMESPACE_NOT_FOUND = 10002
+
+local function version_of(tuple)
+    return tuple.version or 0
+end
+
+-- Ожидаемая версия кортежа без версии, см. Unversioned в internal/db
+local UNVERSIONED = 18446744073709551615ULL
+
+-- Проверка ожидаемой версии. 0 или nil означают, что версия не проверяется.
+-- Проверка и последующая запись выполняются без передачи управления
+-- другим файберам, поэтому между ними не может вклиниться чужая запись.
+local function check_version(tuple, expected_version)
+    if expected_version == nil or expected_version == 0 then
+        return
+    end
+    if expected_version == UNVERSIONED then
+        expected_version = 0
+    end
+    if version_of(tuple) ~= expected_version then
+        box.error{code = ERR_VERSION_MISMATCH, reason = 'version mismatch'}
+    end
+end
+
+local function now()
+    return math.floor(fiber.time())
+end
+
+local function is_expired(tuple)
+    return tuple.expires_at ~= nil and tuple.expires_at <= now()
+end
+
+-- Время истечения для записи в кортеж: 0 или nil означают бессрочный ключ
+local function expiry(expires_at)
+    if expires_at == nil or expires_at == 0 then
+        return box.NULL
+    end
+    return expires_at
+end
+
+-- Имена space пространства имен. Ключи default остаются в space,
+-- созданных до появления пространств имен.
+local function space_names(ns)
+    if ns == DEFAULT_NAMESPACE then
+        return {kv = 'kv', history = 'kv_history', trash = 'kv_trash'}
+    end
+    return {kv = 'kv_ns_' .. ns, history = 'kv_history_ns_' .. ns, trash = 'kv_trash_ns_' .. ns}
+end
+
+-- Возвращает space пространства имен ns: kv, history и trash.
+-- Пустое имя означает default.
+local function namespace(ns)
+    if ns == nil or ns == '' then
+        ns = DEFAULT_NAMESPACE
+    end
+    if box.space.kv_namespaces:get(ns) == nil then
+        box.error{code = ERR_NAMESPACE_NOT_FOUND, reason = 'namespace not found'}
+    end
+    local names = space_names(ns)
+    return {
+        name = ns,
+        kv = box.space[names.kv],
+        history = box.space[names.history],
+        trash = box.space[names.trash]
+    }
+end
+
+-- Возвращает кортеж ключа, если он существует и не истек.
+-- Истекшие ключи невидимы сразу, даже если фоновый файбер еще не удалил их.
+local function get_alive(s, key)
+    local tuple = s.kv:get(key)
+    if tuple == nil or is_expired(tuple) then
+        return nil
+    end
+    return tuple
+end
+
+-- Удаляет из истории ключа первые count версий, или все, если count не задан
+local function trim_history(history, key, count)
+    local versions = {}
+    for _, entry in history:pairs({key}) do
+        if count ~= nil and #versions >= count then
+            break
+        end
+        table.insert(versions, entry.version)
+    end
+    for _, version in ipairs(versions) do
+        history:delete{key, version}
+    end
+end
+
+-- Ключ, созданный с первой версией, начинает историю заново: версии прежнего
+-- ключа с тем же именем нумеровались бы так же. Ключ, восстановленный
+-- из корзины, продолжает нумерацию и сохраняет историю.
+-- История удаленного ключа нужна, только пока он лежит в корзине,
+-- иначе она удаляется вместе с ключом.
+local function log_history(s, change_type, tuple)
+    if change_type == 'delete' then
+        if s.trash:get(tuple.key) == nil then
+            trim_history(s.history, tuple.key)
+        end
+        return
+    end
+    if history_size <= 0 then
+        return
+    end
+    local history = s.history
+    if change_type == 'create' and version_of(tuple) == 1 then
+        trim_history(history, tuple.key)
+    end
+    history:replace{tuple.key, version_of(tuple), tuple.value, now()}
+
+    local excess = history:count({tuple.key}) - history_size
+    if excess > 0 then
+        trim_history(history, tuple.key, excess)
+    end
+end
+
+-- Запись в журнал изменений и историю выполняется триггером в той же
+-- транзакции, что и изменение ключа, поэтому журнал учитывает все пути записи,
+-- включая пакеты и фоновое удаление истекших ключей.
+-- Замена истекшего кортежа считается созданием ключа.
+local function log_change(ns, old, new)
+    local change_type, tuple = 'update', new
+    if new == nil then
+        change_type, tuple = 'delete', old
+    elseif old == nil or is_expired(old) then
+        change_type = 'create'
+    end
+    box.space.kv_changelog:insert{
+        box.sequence.kv_revision:next(), change_type, tuple.key, tuple.version, tuple.value, now(), ns
+    }
+    local names = space_names(ns)
+    log_history({history = box.space[names.history], trash = box.space[names.trash]}, change_type, tuple)
+end
+
+-- Триггеры не сохраняются между перезапусками, поэтому устанавливаются
+-- при каждом запуске для всех пространств имен
+local function watch_namespace(ns)
+    box.space[space_names(ns).kv]:on_replace(function(old, new)
+        log_change(ns, old, new)
+    end)
+end
+for _, entry in box.space.kv_namespaces:pairs() do
+    watch_namespace(entry.name)
+end
+
+-- Кортеж первой версии нового ключа
+local function new_tuple(key, value, expires_at, labels)
+    local ts = now()
+    if labels == nil then
+        labels = box.NULL
+    end
+    return {key, value, 1, expiry(expires_at), ts, ts, labels}
+end
+
+-- Кортеж следующей версии ключа current: время создания сохраняется,
+-- метки заменяются, только если они переданы
+local function next_tuple(current, value, expires_at, labels)
+    if labels == nil then
+        labels = current.labels
+    end
+    if labels == nil then
+        labels = box.NULL
+    end
+    local created_at = current.created_at
+    if created_at == nil then
+        created_at = box.NULL
+    end
+    return {current.key, value, version_of(current) + 1, expiry(expires_at), created_at, now(), labels}
 end
 
 -- Функция вставки
-function insert_kv(key, value)
-    if box.space.kv:get(key) then
-        error("key already exists")
+-- Для существующего ключа insert сам выбрасывает ошибку ER_TUPLE_FOUND,
+-- по коду которой Go-клиент возвращает db.ErrAlreadyExists.
+-- Истекший, но еще не удаленный ключ перезаписывается.
+local function insert(s, key, value, expires_at, labels)
+    local current = s.kv:get(key)
+    if current ~= nil and is_expired(current) then
+        s.kv:delete(key)
     end
-    return box.space.kv:insert{key, value}
+    return s.kv:insert(new_tuple(key, value, expires_at, labels))
+end
+
+function insert_kv(ns, key, value, expires_at, labels)
+    return insert(namespace(ns), key, value, expires_at, labels)
 end
 
 -- Функция получения значения
-function get_kv(key)
-    local result = box.space.kv:get(key)
+function get_kv(ns, key)
+    local result = get_alive(namespace(ns), key)
     if result then
         return result
     else
@@ -31,35 +423,558 @@ function get_kv(key)
     end
 end
 
--- Функция удаления
-function delete_kv(key)
-    return box.space.kv:delete(key)
+-- Выполняет fn в транзакции, если она еще не начата, например batch_kv
+local function atomically(fn, ...)
+    if box.is_in_txn() then
+        return fn(...)
+    end
+    return box.atomic(fn, ...)
+end
+
+-- Функция удаления с необязательной проверкой версии.
+-- Возвращает удаленный кортеж или nil, если ключа нет.
+-- При включенном мягком удалении ключ в той же транзакции переносится в корзину.
+local function delete(s, key, expected_version)
+    local current = get_alive(s, key)
+    if not current then
+        return nil
+    end
+    check_version(current, expected_version)
+    if trash_retention <= 0 then
+        return s.kv:delete(key)
+    end
+    return atomically(function()
+        s.trash:replace{
+            key, current.value, version_of(current), now(),
+            current.labels or box.NULL, current.created_at or box.NULL
+        }
+        return s.kv:delete(key)
+    end)
+end
+
+function delete_kv(ns, key, expected_version)
+    return delete(namespace(ns), key, expected_version)
 end
 
--- Функция обновления
-function update_kv(key, value)
-    if not box.space.kv:get(key) then
+-- Функция обновления с необязательной проверкой версии.
+-- Время истечения заменяется переданным, без него ключ становится бессрочным.
+local function update(s, key, value, expected_version, expires_at, labels)
+    local current = get_alive(s, key)
+    if not current then
         return nil, "key not found"
     end
-    return box.space.kv:put{key, value}
+    check_version(current, expected_version)
+    return s.kv:replace(next_tuple(current, value, expires_at, labels))
+end
+
+function update_kv(ns, key, value, expected_version, expires_at, labels)
+    return update(namespace(ns), key, value, expected_version, expires_at, labels)
+end
+
+-- Функция записи с явным режимом:
+-- upsert создает или заменяет ключ, create только создает, replace только заменяет.
+-- Возвращает кортеж и признак того, что ключ был создан.
+-- Ненулевая ожидаемая версия требует существующего ключа с этой версией.
+function put_kv(ns, key, value, mode, expected_version, expires_at, labels)
+    if mode ~= 'upsert' and mode ~= 'create' and mode ~= 'replace' then
+        error('unknown put mode ' .. tostring(mode))
+    end
+
+    local s = namespace(ns)
+    local current = get_alive(s, key)
+    if current ~= nil then
+        if mode == 'create' then
+            -- insert выбрасывает ER_TUPLE_FOUND, как и в insert_kv
+            return s.kv:insert(new_tuple(key, value, expires_at, labels))
+        end
+        check_version(current, expected_version)
+        return s.kv:replace(next_tuple(current, value, expires_at, labels)), false
+    end
+
+    if mode == 'replace' then
+        return nil, false
+    end
+    if expected_version ~= nil and expected_version ~= 0 then
+        box.error{code = ERR_VERSION_MISMATCH, reason = 'version mismatch'}
+    end
+    -- replace перезаписывает истекший, но еще не удаленный кортеж
+    return s.kv:replace(new_tuple(key, value, expires_at, labels)), true
+end
+
+-- Проверка меток по селектору - списку требований {key, op, value},
+-- см. LabelSelector в internal/db
+local function labels_match(labels, selector)
+    for _, req in ipairs(selector or {}) do
+        local key, op, expected = req[1], req[2], req[3]
+        local value = nil
+        if labels ~= nil then
+            value = labels[key]
+        end
+        if (op == '=' and value ~= expected)
+                or (op == '!=' and value == expected)
+                or (op == 'exists' and value == nil)
+                or (op == '!exists' and value ~= nil) then
+            return false
+        end
+    end
+    return true
+end
+
+-- Функция листинга: до limit кортежей с префиксом prefix и метками,
+-- подходящими к selector, начиная с ключа, следующего за after
+-- (курсор предыдущей страницы)
+function list_kv(ns, prefix, after, limit, selector)
+    local start, iterator = prefix, 'GE'
+    if after ~= nil and after ~= '' and after >= prefix then
+        start, iterator = after, 'GT'
+    end
+
+    local result = {}
+    for _, tuple in namespace(ns).kv.index.ordered:pairs(start, {iterator = iterator}) do
+        if #result >= limit or tuple[1]:sub(1, #prefix) ~= prefix then
+            break
+        end
+        if not is_expired(tuple) and labels_match(tuple.labels, selector) then
+            table.insert(result, tuple)
+        end
+    end
+    return result
+end
+
+-- Код ошибки операции пакета для Go-клиента, см. batchError в internal/db
+local function batch_error_code(err)
+    if type(err) == 'cdata' and err.code == box.error.TUPLE_FOUND then
+        return 'already_exists'
+    end
+    if type(err) == 'cdata' and err.code == ERR_VERSION_MISMATCH then
+        return 'version_mismatch'
+    end
+    return tostring(err)
+end
+
+local function batch_op(s, op)
+    if op.op == 'get' then
+        return get_alive(s, op.key)
+    elseif op.op == 'create' then
+        return insert(s, op.key, op.value, op.expires_at, op.labels)
+    elseif op.op == 'update' then
+        return update(s, op.key, op.value, op.if_version, op.expires_at, op.labels)
+    elseif op.op == 'delete' then
+        return delete(s, op.key, op.if_version)
+    end
+    error('unknown batch operation ' .. tostring(op.op))
+end
+
+-- Функция пакетного выполнения операций.
+-- Для каждой операции возвращается пара {tuple, error_code}.
+-- Атомарный пакет выполняется в одной транзакции и при первой ошибке
+-- откатывается, результаты остальных операций не возвращаются.
+-- Отсутствие ключа в get ошибкой пакета не считается.
+function batch_kv(ns, ops, atomic)
+    local s = namespace(ns)
+    if atomic then
+        box.begin()
+    end
+
+    local results = {}
+    for _, op in ipairs(ops) do
+        local ok, tuple = pcall(batch_op, s, op)
+        if not ok then
+            table.insert(results, {box.NULL, batch_error_code(tuple)})
+            if atomic then
+                box.rollback()
+                return results
+            end
+        elseif tuple == nil then
+            table.insert(results, {box.NULL, 'not_found'})
+            if atomic and op.op ~= 'get' then
+                box.rollback()
+                return results
+            end
+        else
+            table.insert(results, {tuple, box.NULL})
+        end
+    end
+
+    if atomic then
+        box.commit()
+    end
+    return results
+end
+
+-- Функция чтения истории ключа: версии от новой к старой
+function history_kv(ns, key)
+    return namespace(ns).history:select({key}, {iterator = 'REQ'})
+end
+
+-- Функция получения версии ключа из истории
+function get_version_kv(ns, key, version)
+    return namespace(ns).history:get{key, version}
+end
+
+-- Функция листинга корзины, аналог list_kv
+function list_trash_kv(ns, prefix, after, limit, selector)
+    local start, iterator = prefix, 'GE'
+    if after ~= nil and after ~= '' and after >= prefix then
+        start, iterator = after, 'GT'
+    end
+
+    local result = {}
+    for _, tuple in namespace(ns).trash.index.primary:pairs(start, {iterator = iterator}) do
+        if #result >= limit or tuple.key:sub(1, #prefix) ~= prefix then
+            break
+        end
+        if labels_match(tuple.labels, selector) then
+            table.insert(result, tuple)
+        end
+    end
+    return result
+end
+
+-- Функция восстановления ключа из корзины.
+-- Ключ получает следующую версию, поэтому его история продолжается, и не
+-- получает TTL. Метки и время создания сохраняются. Если ключ с тем же
+-- именем существует, insert выбрасывает ER_TUPLE_FOUND.
+function restore_trash_kv(ns, key)
+    local s = namespace(ns)
+    local trashed = s.trash:get(key)
+    if trashed == nil then
+        return nil
+    end
+    return atomically(function()
+        local current = s.kv:get(key)
+        if current ~= nil and is_expired(current) then
+            s.kv:delete(key)
+        end
+        local restored = s.kv:insert{
+            key, trashed.value, trashed.version + 1, box.NULL,
+            trashed.created_at or box.NULL, now(), trashed.labels or box.NULL
+        }
+        s.trash:delete(key)
+        return restored
+    end)
+end
+
+-- Ревизия последней удаленной компакцией записи пространства имен ns или,
+-- без ns, всего журнала. Компакция записей одного пространства не мешает
+-- читать журнал другого.
+local function changelog_compacted(ns)
+    local meta = box.space.kv_meta:get(ns and compacted_meta_name(ns) or 'changelog_compacted')
+    return meta and meta.value or 0
+end
+
+-- Ревизия последней записи журнала, в том числе удаленной компакцией
+local function changelog_head()
+    local head = changelog_compacted()
+    local last = box.space.kv_changelog.index.primary:max()
+    if last ~= nil and last.revision > head then
+        head = last.revision
+    end
+    return head
+end
+
+-- Функция чтения журнала: до limit записей пространства имен ns
+-- с ревизией больше since. Журнал общий для всех пространств имен,
+-- записи ns читаются по индексу namespace.
+-- Вторым значением возвращается ревизия последней удаленной компакцией
+-- записи ns: если since меньше нее, часть изменений уже потеряна. Третьим -
+-- ревизия, с которой продолжать чтение: последняя прочитанная запись или,
+-- если записи ns прочитаны до конца, последняя запись журнала.
+-- С from_head записи не читаются.
+function changes_kv(ns, since, limit, from_head)
+    ns = namespace(ns).name
+    local head = changelog_head()
+    if from_head then
+        return {}, changelog_compacted(ns), head
+    end
+
+    local result = {}
+    for _, entry in box.space.kv_changelog.index.namespace:pairs({ns, since}, {iterator = 'GT'}) do
+        if #result >= limit or entry.namespace ~= ns then
+            break
+        end
+        table.insert(result, entry)
+    end
+
+    local last = since
+    if #result >= limit then
+        last = result[#result].revision
+    elseif head > since then
+        last = head
+    end
+    return result, changelog_compacted(ns), last
+end
+
+-- Допустимое имя пространства имен, см. ValidateNamespace в internal/db.
+-- Имя входит в имена space, поэтому проверяется и здесь.
+local function check_namespace_name(ns)
+    if type(ns) ~= 'string' or #ns > 63 or not ns:match('^[a-z0-9][a-z0-9_%-]*$') then
+        error('invalid namespace name ' .. tostring(ns))
+    end
+end
+
+-- Функция создания пространства имен вместе с его space ключей, истории
+-- и корзины. Создание space требует прав администратора, поэтому функция
+-- регистрируется с setuid.
+function create_namespace_kv(ns)
+    check_namespace_name(ns)
+    if box.space.kv_namespaces:get(ns) ~= nil then
+        -- insert выбрасывает ER_TUPLE_FOUND, как и при создании существующего ключа
+        return box.space.kv_namespaces:insert{ns, now()}
+    end
+
+    local names = space_names(ns)
+    local kv = box.schema.space.create(names.kv, {if_not_exists = true, format = kv_format})
+    kv:create_index('primary', {type = 'hash', parts = {'key'}, if_not_exists = true})
+    create_kv_indexes(kv)
+    create_history_space(names.history)
+    create_trash_space(names.trash)
+    for _, name in pairs(names) do
+        box.schema.user.grant('guest', 'read,write', 'space', name, {if_not_exists = true})
+    end
+    watch_namespace(ns)
+    return box.space.kv_namespaces:insert{ns, now()}
+end
+
+-- Функция листинга пространств имен в порядке возрастания имени
+function list_namespaces_kv()
+    return box.space.kv_namespaces:select()
+end
+
+-- Функция получения пространства имен из реестра. Возвращает nil,
+-- если пространства нет.
+function get_namespace_kv(ns)
+    return box.space.kv_namespaces:get(ns)
+end
+
+-- Функция удаления пространства имен со всеми его ключами, историей и
+-- корзиной. Возвращает nil, если пространства нет. Записи журнала
+-- изменений остаются до компакции.
+function drop_namespace_kv(ns)
+    if ns == DEFAULT_NAMESPACE then
+        error('default namespace cannot be dropped')
+    end
+    if box.space.kv_namespaces:get(ns) == nil then
+        return nil
+    end
+    box.space.kv_namespaces:delete(ns)
+    for _, name in pairs(space_names(ns)) do
+        if box.space[name] ~= nil then
+            box.space[name]:drop()
+        end
+    end
+    return true
 end
 
 -- Регистрация функций
 
-box.schema.func.create('insert_kv')
-box.schema.func.create('get_kv')
-box.schema.func.create('update_kv')
-box.schema.func.create('delete_kv')
+box.schema.func.create('insert_kv', {if_not_exists = true})
+box.schema.func.create('get_kv', {if_not_exists = true})
+box.schema.func.create('update_kv', {if_not_exists = true})
+box.schema.func.create('delete_kv', {if_not_exists = true})
+box.schema.func.create('list_kv', {if_not_exists = true})
+box.schema.func.create('batch_kv', {if_not_exists = true})
+box.schema.func.create('put_kv', {if_not_exists = true})
+box.schema.func.create('changes_kv', {if_not_exists = true})
+box.schema.func.create('history_kv', {if_not_exists = true})
+box.schema.func.create('get_version_kv', {if_not_exists = true})
+box.schema.func.create('list_trash_kv', {if_not_exists = true})
+box.schema.func.create('restore_trash_kv', {if_not_exists = true})
+box.schema.func.create('create_namespace_kv', {setuid = true, if_not_exists = true})
+box.schema.func.create('list_namespaces_kv', {if_not_exists = true})
+box.schema.func.create('get_namespace_kv', {if_not_exists = true})
+box.schema.func.create('drop_namespace_kv', {setuid = true, if_not_exists = true})
 
 -- Права гостю на выполнение этих функций
 
-box.schema.user.grant('guest', 'execute', 'function', 'insert_kv')
-box.schema.user.grant('guest', 'execute', 'function', 'get_kv')
-box.schema.user.grant('guest', 'execute', 'function', 'update_kv')
-box.schema.user.grant('guest', 'execute', 'function', 'delete_kv')
+box.schema.user.grant('guest', 'execute', 'function', 'insert_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'get_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'update_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'delete_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'list_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'batch_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'put_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'changes_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'history_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'get_version_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'list_trash_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'restore_trash_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'create_namespace_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'list_namespaces_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'get_namespace_kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'execute', 'function', 'drop_namespace_kv', {if_not_exists = true})
 
 -- Права гостю на чтение и запись в space.kv
 
-box.schema.user.grant('guest', 'read,write', 'space', 'kv')
+box.schema.user.grant('guest', 'read,write', 'space', 'kv', {if_not_exists = true})
+box.schema.user.grant('guest', 'read,write', 'space', 'kv_changelog', {if_not_exists = true})
+box.schema.user.grant('guest', 'read,write', 'space', 'kv_history', {if_not_exists = true})
+box.schema.user.grant('guest', 'read,write', 'space', 'kv_trash', {if_not_exists = true})
+box.schema.user.grant('guest', 'read', 'space', 'kv_meta', {if_not_exists = true})
+box.schema.user.grant('guest', 'read', 'space', 'kv_namespaces', {if_not_exists = true})
+box.schema.user.grant('guest', 'read,write', 'sequence', 'kv_revision', {if_not_exists = true})
+
+-- Фоновое удаление истекших ключей.
+-- Интервал проверки в секундах задается переменной KV_EXPIRE_INTERVAL.
+local expire_interval = tonumber(os.getenv('KV_EXPIRE_INTERVAL')) or 1
+local expire_batch_size = 1000
+
+local function expire_batch(s)
+    local keys = {}
+    for _, tuple in s.kv.index.expires:pairs({0}, {iterator = 'GE'}) do
+        if tuple.expires_at > now() or #keys >= expire_batch_size then
+            break
+        end
+        table.insert(keys, tuple.key)
+    end
+
+    -- Удаление передает управление другим файберам, поэтому перед ним
+    -- кортеж перечитывается: ключ мог быть перезаписан с новым TTL
+    for _, key in ipairs(keys) do
+        local tuple = s.kv:get(key)
+        if tuple ~= nil and is_expired(tuple) then
+            s.kv:delete(key)
+        end
+    end
+    return #keys
+end
+
+-- Выполняет fn для каждого пространства имен и возвращает наибольший
+-- результат: полный батч хотя бы в одном пространстве означает, что
+-- следующий проход нужно начать без паузы
+local function each_namespace(fn)
+    local max = 0
+    for _, entry in ipairs(box.space.kv_namespaces:select()) do
+        -- Пространство могло быть удалено, пока файбер уступал управление
+        if box.space.kv_namespaces:get(entry.name) ~= nil then
+            max = math.max(max, fn(namespace(entry.name)))
+        end
+    end
+    return max
+end
+
+fiber.create(function()
+    fiber.name('kv_expiration')
+    while true do
+        local expired = 0
+        if not box.info.ro then
+            local ok, result = pcall(each_namespace, expire_batch)
+            if ok then
+                expired = result
+            else
+                log.error('kv expiration failed: %s', result)
+            end
+        end
+        -- Полный батч означает, что истекших ключей может быть больше
+        if expired < expire_batch_size then
+            fiber.sleep(expire_interval)
+        end
+    end
+end)
+
+-- Компакция журнала изменений. Удаляются записи старше
+-- KV_CHANGELOG_RETENTION секунд (по умолчанию 7 дней) и записи сверх
+-- KV_CHANGELOG_MAX_ENTRIES (по умолчанию 1000000), проверка выполняется
+-- каждые KV_CHANGELOG_COMPACT_INTERVAL секунд.
+local changelog_retention = tonumber(os.getenv('KV_CHANGELOG_RETENTION')) or 7 * 24 * 3600
+local changelog_max_entries = tonumber(os.getenv('KV_CHANGELOG_MAX_ENTRIES')) or 1000000
+local changelog_compact_interval = tonumber(os.getenv('KV_CHANGELOG_COMPACT_INTERVAL')) or 60
+local changelog_batch_size = 1000
+
+local function compact_changelog()
+    local excess = box.space.kv_changelog:len() - changelog_max_entries
+    local deadline = now() - changelog_retention
+    local revisions = {}
+    -- Последняя удаляемая ревизия каждого пространства имен
+    local compacted = {}
+    for _, entry in box.space.kv_changelog.index.primary:pairs() do
+        if #revisions >= changelog_batch_size
+                or (#revisions >= excess and entry.timestamp > deadline) then
+            break
+        end
+        table.insert(revisions, entry.revision)
+        compacted[entry.namespace or DEFAULT_NAMESPACE] = entry.revision
+    end
+    if #revisions == 0 then
+        return 0
+    end
+
+    -- Удаление и отметки компакции фиксируются вместе, чтобы читатель
+    -- не пропустил удаленные записи
+    box.begin()
+    for _, revision in ipairs(revisions) do
+        box.space.kv_changelog:delete(revision)
+    end
+    for ns, revision in pairs(compacted) do
+        box.space.kv_meta:replace{compacted_meta_name(ns), revision}
+    end
+    box.space.kv_meta:replace{'changelog_compacted', revisions[#revisions]}
+    box.commit()
+    return #revisions
+end
+
+fiber.create(function()
+    fiber.name('kv_changelog_compaction')
+    while true do
+        local compacted = 0
+        if not box.info.ro then
+            local ok, result = pcall(compact_changelog)
+            if ok then
+                compacted = result
+            else
+                box.rollback()
+                log.error('kv changelog compaction failed: %s', result)
+            end
+        end
+        if compacted < changelog_batch_size then
+            fiber.sleep(changelog_compact_interval)
+        end
+    end
+end)
+
+-- Очистка корзины от ключей, срок хранения которых истек, вместе с их
+-- историей. История ключа, созданного заново, сохраняется.
+local function purge_trash(s)
+    local keys = {}
+    local deadline = now() - trash_retention
+    for _, tuple in s.trash.index.deleted_at:pairs() do
+        if tuple.deleted_at > deadline or #keys >= expire_batch_size then
+            break
+        end
+        table.insert(keys, tuple.key)
+    end
+    for _, key in ipairs(keys) do
+        local tuple = s.trash:get(key)
+        if tuple ~= nil and tuple.deleted_at <= deadline then
+            box.atomic(function()
+                s.trash:delete(key)
+                if s.kv:get(key) == nil then
+                    trim_history(s.history, key)
+                end
+            end)
+        end
+    end
+    return #keys
+end
+
+if trash_retention > 0 then
+    fiber.create(function()
+        fiber.name('kv_trash_purge')
+        while true do
+            local purged = 0
+            if not box.info.ro then
+                local ok, result = pcall(each_namespace, purge_trash)
+                if ok then
+                    purged = result
+                else
+                    log.error('kv trash purge failed: %s', result)
+                end
+            end
+            if purged < expire_batch_size then
+                fiber.sleep(expire_interval)
+            end
+        end
+    end)
+end
 
 print("Tarantool KV storage initialized")
\ No newline at end of file
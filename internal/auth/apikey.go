@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader - заголовок с API-ключом для клиентов, которые не могут
+// передать его как bearer-токен
+const APIKeyHeader = "X-API-Key"
+
+// hashPrefix - префикс хеша ключа в файле, оставляющий место для других алгоритмов
+const hashPrefix = "sha256:"
+
+// APIKeyConfig - запись файла ключей
+type APIKeyConfig struct {
+	// Name - имя клиента, под которым его запросы попадают в логи
+	Name string `json:"name"`
+	// Hash - "sha256:" и hex SHA-256 ключа, сами ключи на сервере не хранятся
+	Hash string `json:"hash"`
+}
+
+// APIKeysFile - файл ключей вида {"keys": [{"name": "...", "hash": "sha256:..."}]}
+type APIKeysFile struct {
+	Keys []APIKeyConfig `json:"keys"`
+}
+
+// APIKeys проверяет API-ключи по их хешам
+type APIKeys struct {
+	byHash map[string]*Principal
+}
+
+var _ Authenticator = (*APIKeys)(nil)
+
+// HashAPIKey возвращает хеш ключа в формате файла ключей
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys читает файл ключей
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	var file APIKeysFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file %s: %w", path, err)
+	}
+	return NewAPIKeys(file.Keys)
+}
+
+// NewAPIKeys проверяет записи и строит индекс ключей по хешу
+func NewAPIKeys(keys []APIKeyConfig) (*APIKeys, error) {
+	a := &APIKeys{byHash: make(map[string]*Principal, len(keys))}
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key %d: name is required", i)
+		}
+		hash := strings.ToLower(key.Hash)
+		digest, ok := strings.CutPrefix(hash, hashPrefix)
+		if !ok {
+			return nil, fmt.Errorf("API key %q: hash must start with %q", key.Name, hashPrefix)
+		}
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: hash must be a hex SHA-256 digest", key.Name)
+		}
+		if _, ok := a.byHash[hash]; ok {
+			return nil, fmt.Errorf("API key %q: duplicate hash", key.Name)
+		}
+		a.byHash[hash] = &Principal{Name: key.Name}
+	}
+	return a, nil
+}
+
+// Authenticate ищет ключ из заголовка X-API-Key или Authorization: Bearer.
+// Ключи сравниваются по хешу, поэтому время проверки не зависит от
+// совпадения префикса ключа.
+func (a *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key = bearerToken(r)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	p, ok := a.byHash[HashAPIKey(key)]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return p, nil
+}
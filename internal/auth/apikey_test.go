@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func writeKeysFile(t *testing.T, keys []auth.APIKeyConfig) string {
+	t.Helper()
+	raw, err := json.Marshal(auth.APIKeysFile{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMiddleware_APIKeys(t *testing.T) {
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	path := writeKeysFile(t, []auth.APIKeyConfig{{Name: "billing", Hash: auth.HashAPIKey("secret")}})
+	keys, err := auth.LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(auth.Middleware(keys))
+	r.GET("/kv", func(c *gin.Context) {
+		c.String(http.StatusOK, auth.FromContext(c.Request.Context()).Name)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{name: "bearer", header: "Authorization", value: "Bearer secret", status: http.StatusOK},
+		{name: "header", header: auth.APIKeyHeader, value: "secret", status: http.StatusOK},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "wrong key", header: auth.APIKeyHeader, value: "other", status: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Authorization", value: "Basic secret", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/kv", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "billing" {
+				t.Errorf("expected caller billing, got %q", w.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys_InvalidHash(t *testing.T) {
+	path := writeKeysFile(t, []auth.APIKeyConfig{{Name: "billing", Hash: "plain-text-key"}})
+	if _, err := auth.LoadAPIKeys(path); err == nil {
+		t.Error("expected error for a key without sha256 hash")
+	}
+}
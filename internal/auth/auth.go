@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNoCredentials возвращается, если запрос не содержит учетных данных
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials возвращается, если учетные данные не прошли проверку
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal - аутентифицированный клиент
+type Principal struct {
+	// Name - имя клиента, которое попадает в логи
+	Name string
+	// Rules - разрешения, выданные вместе с учетными данными, например
+	// из claim JWT. Они сужают права по политике, но не расширяют их.
+	// nil означает, что права определяет только политика.
+	Rules []Rule
+}
+
+// Authenticator определяет клиента по учетным данным запроса.
+// Если их нет, возвращается ErrNoCredentials, если они неверны - ErrInvalidCredentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с клиентом запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает клиента запроса или nil, если аутентификация выключена
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer <token>
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Chain проверяет запрос по очереди каждым Authenticator и возвращает
+// первого найденного клиента. Неверные учетные данные сразу отклоняют запрос.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+type chain []Authenticator
+
+func (ch chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range ch {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
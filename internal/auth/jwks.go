@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// JWTKey - ключ проверки подписи JWT. Key - []byte для HS256,
+// *rsa.PublicKey для RS256 или *ecdsa.PublicKey на кривой P-256 для ES256.
+// Пустой ID подходит к токену с любым kid.
+type JWTKey struct {
+	ID  string
+	Key interface{}
+}
+
+// LoadHMACKeyFile читает общий секрет HS256. Пробелы и перевод строки
+// в конце файла отбрасываются.
+func LoadHMACKeyFile(path string) (JWTKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("failed to read HMAC key file: %w", err)
+	}
+	secret := bytes.TrimSpace(raw)
+	if err := checkJWTKey(secret); err != nil {
+		return JWTKey{}, fmt.Errorf("HMAC key file %s: %w", path, err)
+	}
+	return JWTKey{Key: secret}, nil
+}
+
+// LoadPublicKeyFile читает открытый ключ RSA или ECDSA в PEM:
+// "PUBLIC KEY", "RSA PUBLIC KEY" или сертификат
+func LoadPublicKeyFile(path string) (JWTKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("failed to read public key file: %w", err)
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return JWTKey{}, fmt.Errorf("public key file %s is not PEM", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return JWTKey{}, fmt.Errorf("public key file %s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("failed to parse public key file %s: %w", path, err)
+	}
+
+	if err := checkJWTKey(key); err != nil {
+		return JWTKey{}, fmt.Errorf("public key file %s: %w", path, err)
+	}
+	return JWTKey{Key: key}, nil
+}
+
+// jwk - ключ из JWKS (RFC 7517) с полями для RSA, EC и oct
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// LoadJWKSFile читает набор ключей JWKS вида {"keys": [...]}.
+// Ключи с "use", отличным от "sig", пропускаются.
+func LoadJWKSFile(path string) ([]JWTKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", path, err)
+	}
+
+	var keys []JWTKey
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS file %s key %d: %w", path, i, err)
+		}
+		keys = append(keys, JWTKey{ID: k.Kid, Key: key})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no signing keys", path)
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid e")
+		}
+		key := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		return key, checkJWTKey(key)
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return key, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid k: %w", err)
+		}
+		return secret, checkJWTKey(secret)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// checkJWTKey отклоняет ключи, которые не подходят ни к одному
+// поддерживаемому алгоритму или слишком слабы
+func checkJWTKey(key interface{}) error {
+	switch k := key.(type) {
+	case []byte:
+		if len(k) < 32 {
+			return errors.New("HMAC key must be at least 32 bytes")
+		}
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return errors.New("RSA key must be at least 2048 bits")
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return errors.New("ECDSA key must use curve P-256")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultJWTLeeway - допустимое расхождение часов сервера и издателя токенов
+const DefaultJWTLeeway = 30 * time.Second
+
+// JWTGrant - элемент claim "kv": действия Verbs с ключами, начинающимися
+// с одного из Prefixes, в пространствах имен Namespaces (пусто - в любых)
+type JWTGrant struct {
+	Verbs      []Verb   `json:"verbs"`
+	Prefixes   []string `json:"prefixes"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// JWT проверяет bearer-токены HS256, RS256 и ES256 по заранее загруженным ключам
+type JWT struct {
+	keys     []JWTKey
+	audience string
+	leeway   time.Duration
+}
+
+var _ Authenticator = (*JWT)(nil)
+
+// JWTOption настраивает проверку токенов
+type JWTOption func(*JWT)
+
+// WithAudience требует, чтобы claim "aud" содержал audience
+func WithAudience(audience string) JWTOption {
+	return func(j *JWT) {
+		j.audience = audience
+	}
+}
+
+// WithLeeway задает допустимое расхождение часов при проверке exp и nbf
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(j *JWT) {
+		j.leeway = leeway
+	}
+}
+
+// NewJWT создает проверку токенов по ключам keys
+func NewJWT(keys []JWTKey, opts ...JWTOption) (*JWT, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one JWT key is required")
+	}
+	for i, key := range keys {
+		if err := checkJWTKey(key.Key); err != nil {
+			return nil, fmt.Errorf("JWT key %d: %w", i, err)
+		}
+	}
+
+	j := &JWT{keys: keys, leeway: DefaultJWTLeeway}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Grants    []JWTGrant      `json:"kv"`
+}
+
+// Authenticate проверяет токен из Authorization: Bearer. Значения без
+// трех частей через точку не считаются JWT и пропускаются, чтобы их
+// могли проверить другие Authenticator, например APIKeys.
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := j.verify(token, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	p := &Principal{Name: claims.Subject}
+	if claims.Grants != nil {
+		p.Rules = make([]Rule, 0, len(claims.Grants))
+		for _, grant := range claims.Grants {
+			p.Rules = append(p.Rules, grant.rule())
+		}
+	}
+	return p, nil
+}
+
+func (j *JWT) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range j.keys {
+		if key.ID != "" && header.Kid != "" && key.ID != header.Kid {
+			continue
+		}
+		if verifySignature(header.Alg, key.Key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("signature is not valid for alg %q", header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("sub claim is required")
+	}
+	// Токен без exp действовал бы бессрочно, и отозвать его можно было бы
+	// только сменой ключа
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("exp claim is required")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(j.leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && now.Add(j.leeway).Before(unixTime(*claims.NotBefore)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if j.audience != "" && !hasAudience(claims.Audience, j.audience) {
+		return nil, fmt.Errorf("token audience does not include %q", j.audience)
+	}
+	for i, grant := range claims.Grants {
+		for _, verb := range grant.Verbs {
+			if !knownVerb(verb) {
+				return nil, fmt.Errorf("kv claim %d: unknown verb %q", i, verb)
+			}
+		}
+		for _, prefix := range grant.Prefixes {
+			if strings.ContainsAny(prefix, "*?") {
+				return nil, fmt.Errorf("kv claim %d: prefix %q must not contain wildcards", i, prefix)
+			}
+		}
+	}
+	return &claims, nil
+}
+
+// verifySignature проверяет подпись, только если тип ключа соответствует
+// алгоритму, поэтому открытый ключ RSA нельзя использовать как секрет HS256
+func verifySignature(alg string, key interface{}, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch k := key.(type) {
+	case []byte:
+		if alg != "HS256" {
+			return false
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// Подпись ES256 - r и s по 32 байта подряд, а не DER
+		if alg != "ES256" || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// unixTime переводит NumericDate из claim в время с точностью до секунды
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+// hasAudience проверяет claim "aud", который может быть строкой или списком строк
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return false
+	}
+	for _, aud := range list {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// rule переводит разрешение из токена в правило политики:
+// префикс "payments/" становится шаблоном "payments/*"
+func (g JWTGrant) rule() Rule {
+	keys := make([]string, 0, len(g.Prefixes))
+	for _, prefix := range g.Prefixes {
+		keys = append(keys, prefix+"*")
+	}
+	return Rule{Verbs: g.Verbs, Keys: keys, Namespaces: g.Namespaces}
+}
@@ -0,0 +1,273 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// signJWT подписывает claims ключом key алгоритмом alg
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/kv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func writePublicKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWT_Algorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPublic, err := auth.LoadPublicKeyFile(writePublicKey(t, &rsaKey.PublicKey))
+	if err != nil {
+		t.Fatalf("load RSA key: %v", err)
+	}
+	ecPublic, err := auth.LoadPublicKeyFile(writePublicKey(t, &ecKey.PublicKey))
+	if err != nil {
+		t.Fatalf("load EC key: %v", err)
+	}
+
+	jwt, err := auth.NewJWT([]auth.JWTKey{{Key: hmacSecret}, rsaPublic, ecPublic})
+	if err != nil {
+		t.Fatalf("new JWT: %v", err)
+	}
+
+	claims := map[string]interface{}{"sub": "billing", "exp": time.Now().Add(time.Hour).Unix()}
+	for _, tt := range []struct {
+		alg string
+		key interface{}
+	}{
+		{alg: "HS256", key: hmacSecret},
+		{alg: "RS256", key: rsaKey},
+		{alg: "ES256", key: ecKey},
+	} {
+		t.Run(tt.alg, func(t *testing.T) {
+			p, err := jwt.Authenticate(bearerRequest(signJWT(t, tt.alg, "", tt.key, claims)))
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if p.Name != "billing" {
+				t.Errorf("expected subject billing, got %q", p.Name)
+			}
+		})
+	}
+
+	// Подпись HS256 открытым ключом RSA как секретом не должна проходить
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := signJWT(t, "HS256", "", der, claims)
+	if _, err := jwt.Authenticate(bearerRequest(forged)); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected algorithm confusion to fail, got %v", err)
+	}
+
+	unsigned := signJWT(t, "none", "", nil, claims)
+	if _, err := jwt.Authenticate(bearerRequest(unsigned)); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected alg none to fail, got %v", err)
+	}
+}
+
+func TestJWT_Claims(t *testing.T) {
+	jwt, err := auth.NewJWT([]auth.JWTKey{{Key: hmacSecret}}, auth.WithAudience("kv"), auth.WithLeeway(0))
+	if err != nil {
+		t.Fatalf("new JWT: %v", err)
+	}
+
+	now := time.Now()
+	exp := now.Add(time.Minute).Unix()
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		valid  bool
+	}{
+		{name: "valid", claims: map[string]interface{}{"sub": "a", "aud": "kv", "exp": exp}, valid: true},
+		{name: "audience list", claims: map[string]interface{}{"sub": "a", "aud": []string{"other", "kv"}, "exp": exp}, valid: true},
+		{name: "expired", claims: map[string]interface{}{"sub": "a", "aud": "kv", "exp": now.Add(-time.Minute).Unix()}},
+		{name: "missing expiry", claims: map[string]interface{}{"sub": "a", "aud": "kv"}},
+		{name: "not yet valid", claims: map[string]interface{}{"sub": "a", "aud": "kv", "exp": exp, "nbf": now.Add(time.Minute).Unix()}},
+		{name: "wrong audience", claims: map[string]interface{}{"sub": "a", "aud": "other", "exp": exp}},
+		{name: "missing audience", claims: map[string]interface{}{"sub": "a", "exp": exp}},
+		{name: "missing subject", claims: map[string]interface{}{"aud": "kv", "exp": exp}},
+		{name: "unknown verb", claims: map[string]interface{}{"sub": "a", "aud": "kv", "exp": exp, "kv": []map[string]interface{}{{"verbs": []string{"drop"}, "prefixes": []string{""}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Authenticate(bearerRequest(signJWT(t, "HS256", "", hmacSecret, tt.claims)))
+			if tt.valid && err != nil {
+				t.Fatalf("expected valid token, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, auth.ErrInvalidCredentials) {
+				t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+			}
+		})
+	}
+
+	// Не-JWT bearer-токен оставляется другим проверкам, например API-ключам
+	if _, err := jwt.Authenticate(bearerRequest("plain-api-key")); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials for non-JWT token, got %v", err)
+	}
+}
+
+func TestJWT_GrantsLimitAccess(t *testing.T) {
+	jwt, err := auth.NewJWT([]auth.JWTKey{{Key: hmacSecret}})
+	if err != nil {
+		t.Fatalf("new JWT: %v", err)
+	}
+
+	token := signJWT(t, "HS256", "", hmacSecret, map[string]interface{}{
+		"sub": "billing",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"kv":  []map[string]interface{}{{"verbs": []string{"get", "update"}, "prefixes": []string{"payments/"}}},
+	})
+	p, err := jwt.Authenticate(bearerRequest(token))
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	// Разрешения из токена действуют и без файла политики
+	var policy *auth.Policy
+	if err := policy.Authorize(p, auth.VerbUpdate, "default", "payments/a/b"); err != nil {
+		t.Errorf("expected update of payments/a/b to be allowed: %v", err)
+	}
+	if err := policy.AuthorizePrefix(p, auth.VerbGet, "default", "payments/2024/"); err != nil {
+		t.Errorf("expected listing payments/2024/ to be allowed: %v", err)
+	}
+	if err := policy.Authorize(p, auth.VerbDelete, "default", "payments/a"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("expected delete to be forbidden, got %v", err)
+	}
+	if err := policy.Authorize(p, auth.VerbGet, "default", "orders/1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("expected other prefix to be forbidden, got %v", err)
+	}
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	point := func(k *ecdsa.PrivateKey) map[string]string {
+		x, y := make([]byte, 32), make([]byte, 32)
+		k.X.FillBytes(x)
+		k.Y.FillBytes(y)
+		return map[string]string{"x": b64(x), "y": b64(y)}
+	}
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "EC", "kid": "current", "crv": "P-256", "x": point(ecKey)["x"], "y": point(ecKey)["y"]},
+		{"kty": "oct", "kid": "shared", "k": b64(hmacSecret)},
+		{"kty": "EC", "kid": "encryption", "use": "enc", "crv": "P-256", "x": point(other)["x"], "y": point(other)["y"]},
+	}}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.LoadJWKSFile(path)
+	if err != nil {
+		t.Fatalf("load JWKS: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 signing keys, got %d", len(keys))
+	}
+
+	jwt, err := auth.NewJWT(keys)
+	if err != nil {
+		t.Fatalf("new JWT: %v", err)
+	}
+	claims := map[string]interface{}{"sub": "billing", "exp": time.Now().Add(time.Hour).Unix()}
+	if _, err := jwt.Authenticate(bearerRequest(signJWT(t, "ES256", "current", ecKey, claims))); err != nil {
+		t.Errorf("expected token signed with current key to pass: %v", err)
+	}
+	if _, err := jwt.Authenticate(bearerRequest(signJWT(t, "HS256", "current", hmacSecret, claims))); err == nil {
+		t.Error("expected token with kid of another key to fail")
+	}
+	if _, err := jwt.Authenticate(bearerRequest(signJWT(t, "ES256", "encryption", other, claims))); err == nil {
+		t.Error("expected token signed with encryption key to fail")
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// log пишет логи пакета с уровнем "auth", см. logger.SetPackageLevel
+var log = logger.Package("auth")
+
+// Middleware пропускает только аутентифицированные запросы и отвечает 401
+// остальным. Клиент запроса сохраняется в контексте, см. FromContext,
+// а его имя добавляется к логгеру запроса полем caller.
+func Middleware(a Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := a.Authenticate(c.Request)
+		if err != nil {
+			log.LogInfo(c.Request.Context(), "Request rejected", logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"reason": err.Error(),
+			})
+			message := "Invalid credentials"
+			if errors.Is(err, ErrNoCredentials) {
+				message = "Authentication required"
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="kv"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.Response{
+				Error: message,
+			})
+			return
+		}
+
+		// Имя клиента попадает во все записи лога этого запроса
+		ctx := logger.WithFields(WithPrincipal(c.Request.Context(), p), logrus.Fields{"caller": p.Name})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
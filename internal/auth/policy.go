@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Verb - действие с ключом, которое разрешает правило политики
+type Verb string
+
+const (
+	VerbGet    Verb = "get"
+	VerbCreate Verb = "create"
+	VerbUpdate Verb = "update"
+	VerbDelete Verb = "delete"
+	// VerbAdmin разрешает управление пространствами имен
+	VerbAdmin Verb = "admin"
+	// VerbAll в правиле разрешает все действия
+	VerbAll Verb = "*"
+)
+
+// Anonymous - имя, под которым политика проверяет запросы без аутентификации
+const Anonymous = "anonymous"
+
+// ErrForbidden возвращается, если политика не разрешает действие.
+// Текст ошибки объясняет причину отказа и предназначен для клиента.
+var ErrForbidden = errors.New("forbidden")
+
+// Rule разрешает действия Verbs с ключами, подходящими под один из шаблонов
+// Keys, в пространствах имен Namespaces. В шаблонах '*' соответствует любой
+// последовательности символов, включая '/', а '?' - одному символу.
+// Пустой Namespaces означает любое пространство.
+type Rule struct {
+	Verbs      []Verb   `json:"verbs"`
+	Keys       []string `json:"keys"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// PolicyFile - файл политики: правила ролей и роли клиентов по имени
+type PolicyFile struct {
+	Roles      map[string][]Rule   `json:"roles"`
+	Principals map[string][]string `json:"principals"`
+}
+
+// Policy проверяет, разрешено ли клиенту действие с ключом
+type Policy struct {
+	roles      map[string][]Rule
+	principals map[string][]string
+}
+
+// LoadPolicy читает файл политики
+func LoadPolicy(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var file PolicyFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	return NewPolicy(file)
+}
+
+// NewPolicy проверяет, что все действия известны, а роли клиентов описаны
+func NewPolicy(file PolicyFile) (*Policy, error) {
+	for role, rules := range file.Roles {
+		for i, rule := range rules {
+			for _, verb := range rule.Verbs {
+				if !knownVerb(verb) {
+					return nil, fmt.Errorf("role %q rule %d: unknown verb %q", role, i, verb)
+				}
+			}
+		}
+	}
+	for principal, roles := range file.Principals {
+		for _, role := range roles {
+			if _, ok := file.Roles[role]; !ok {
+				return nil, fmt.Errorf("principal %q: unknown role %q", principal, role)
+			}
+		}
+	}
+	return &Policy{roles: file.Roles, principals: file.Principals}, nil
+}
+
+// Authorize проверяет действие verb с ключом key в пространстве имен namespace.
+// nil-клиент проверяется как Anonymous. Действие разрешено, если его
+// разрешает роль клиента и, когда у клиента есть правила из токена
+// (см. Principal.Rules), одно из этих правил. nil-политика разрешает все,
+// что не ограничено токеном клиента.
+func (p *Policy) Authorize(principal *Principal, verb Verb, namespace, key string) error {
+	return p.authorize(principal, verb, inNamespace(namespace), func(pattern string) bool {
+		return matchGlob(pattern, key)
+	}, fmt.Sprintf("key %q in namespace %q", key, namespace))
+}
+
+// AuthorizePrefix проверяет действие со всеми ключами с префиксом prefix,
+// например для листинга. Правило подходит, только если его шаблон
+// покрывает любой ключ с этим префиксом.
+func (p *Policy) AuthorizePrefix(principal *Principal, verb Verb, namespace, prefix string) error {
+	return p.authorize(principal, verb, inNamespace(namespace), func(pattern string) bool {
+		return coversPrefix(pattern, prefix)
+	}, fmt.Sprintf("keys with prefix %q in namespace %q", prefix, namespace))
+}
+
+// AuthorizeServer проверяет действие над сервером в целом, например смену
+// уровня логов. Подходят только правила без списка пространств имен,
+// покрывающие все ключи: шаблон пространств вроде "*" или "?" такого
+// права не дает.
+func (p *Policy) AuthorizeServer(principal *Principal, verb Verb) error {
+	anyNamespace := func(r Rule) bool {
+		return len(r.Namespaces) == 0
+	}
+	return p.authorize(principal, verb, anyNamespace, func(pattern string) bool {
+		return coversPrefix(pattern, "")
+	}, "the server")
+}
+
+func (p *Policy) authorize(principal *Principal, verb Verb, scope func(Rule) bool, match func(string) bool, target string) error {
+	name := Anonymous
+	var granted []Rule
+	if principal != nil {
+		name, granted = principal.Name, principal.Rules
+	}
+	forbidden := fmt.Errorf("%w: %s is not allowed to %s %s", ErrForbidden, name, verb, target)
+
+	// Разрешения токена только сужают права: действие должно разрешать
+	// и правило токена, и роль клиента в политике
+	if granted != nil && !anyAllows(granted, verb, scope, match) {
+		return forbidden
+	}
+	if p == nil {
+		return nil
+	}
+	for _, role := range p.principals[name] {
+		if anyAllows(p.roles[role], verb, scope, match) {
+			return nil
+		}
+	}
+	return forbidden
+}
+
+func anyAllows(rules []Rule, verb Verb, scope func(Rule) bool, match func(string) bool) bool {
+	for _, rule := range rules {
+		if rule.allows(verb, scope, match) {
+			return true
+		}
+	}
+	return false
+}
+
+func knownVerb(verb Verb) bool {
+	switch verb {
+	case VerbGet, VerbCreate, VerbUpdate, VerbDelete, VerbAdmin, VerbAll:
+		return true
+	}
+	return false
+}
+
+func (r Rule) allows(verb Verb, scope func(Rule) bool, match func(string) bool) bool {
+	verbOK := false
+	for _, v := range r.Verbs {
+		if v == verb || v == VerbAll {
+			verbOK = true
+			break
+		}
+	}
+	if !verbOK {
+		return false
+	}
+
+	if !scope(r) {
+		return false
+	}
+
+	for _, pattern := range r.Keys {
+		if match(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// inNamespace возвращает проверку, действует ли правило в пространстве
+// имен namespace. Правило без Namespaces действует в любом.
+func inNamespace(namespace string) func(Rule) bool {
+	return func(r Rule) bool {
+		if len(r.Namespaces) == 0 {
+			return true
+		}
+		for _, pattern := range r.Namespaces {
+			if matchGlob(pattern, namespace) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matchGlob сопоставляет строку с шаблоном, в котором '*' - любая
+// последовательность символов, '?' - один символ
+func matchGlob(pattern, s string) bool {
+	// Позиции для возврата к последней '*'
+	star, next := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, i
+			p++
+		case star >= 0:
+			next++
+			p, i = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// coversPrefix сообщает, подходит ли под шаблон любой ключ с префиксом prefix.
+// Проверка консервативна: покрывающими считаются только шаблоны вида
+// "<литерал>*" без других подстановок.
+func coversPrefix(pattern, prefix string) bool {
+	literal, ok := strings.CutSuffix(pattern, "*")
+	if !ok || strings.ContainsAny(literal, "*?") {
+		return false
+	}
+	return strings.HasPrefix(prefix, literal)
+}
@@ -0,0 +1,172 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+)
+
+func testPolicy(t *testing.T) *auth.Policy {
+	t.Helper()
+	policy, err := auth.NewPolicy(auth.PolicyFile{
+		Roles: map[string][]auth.Rule{
+			"reader":          {{Verbs: []auth.Verb{auth.VerbGet}, Keys: []string{"*"}}},
+			"payments-writer": {{Verbs: []auth.Verb{auth.VerbCreate, auth.VerbUpdate}, Keys: []string{"payments/*"}, Namespaces: []string{"default"}}},
+			"public":          {{Verbs: []auth.Verb{auth.VerbGet}, Keys: []string{"public/*"}}},
+		},
+		Principals: map[string][]string{
+			"billing":      {"reader", "payments-writer"},
+			auth.Anonymous: {"public"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new policy: %v", err)
+	}
+	return policy
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy := testPolicy(t)
+	billing := &auth.Principal{Name: "billing"}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		verb      auth.Verb
+		namespace string
+		key       string
+		allowed   bool
+	}{
+		{name: "nested key", principal: billing, verb: auth.VerbUpdate, namespace: "default", key: "payments/a/b", allowed: true},
+		{name: "read any key", principal: billing, verb: auth.VerbGet, namespace: "team", key: "orders/1", allowed: true},
+		{name: "other prefix", principal: billing, verb: auth.VerbUpdate, namespace: "default", key: "orders/1"},
+		{name: "other namespace", principal: billing, verb: auth.VerbCreate, namespace: "team", key: "payments/1"},
+		{name: "verb not granted", principal: billing, verb: auth.VerbDelete, namespace: "default", key: "payments/1"},
+		{name: "anonymous", verb: auth.VerbGet, namespace: "default", key: "public/readme", allowed: true},
+		{name: "anonymous denied", verb: auth.VerbGet, namespace: "default", key: "payments/1"},
+		{name: "unknown principal", principal: &auth.Principal{Name: "intruder"}, verb: auth.VerbGet, namespace: "default", key: "public/readme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.principal, tt.verb, tt.namespace, tt.key)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected access, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_DenialReason(t *testing.T) {
+	err := testPolicy(t).Authorize(&auth.Principal{Name: "billing"}, auth.VerbDelete, "default", "payments/1")
+	if err == nil {
+		t.Fatal("expected denial")
+	}
+	for _, part := range []string{"billing", "delete", `"payments/1"`, `"default"`} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("reason %q does not mention %s", err.Error(), part)
+		}
+	}
+}
+
+func TestPolicy_AuthorizePrefix(t *testing.T) {
+	policy := testPolicy(t)
+	billing := &auth.Principal{Name: "billing"}
+
+	if err := policy.AuthorizePrefix(billing, auth.VerbGet, "default", ""); err != nil {
+		t.Errorf("reader should list all keys: %v", err)
+	}
+	if err := policy.AuthorizePrefix(nil, auth.VerbGet, "default", "public/docs/"); err != nil {
+		t.Errorf("anonymous should list public keys: %v", err)
+	}
+	if err := policy.AuthorizePrefix(nil, auth.VerbGet, "default", "pub"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("prefix wider than the rule must be denied, got %v", err)
+	}
+}
+
+func TestPolicy_TokenRulesNarrowRoles(t *testing.T) {
+	policy := testPolicy(t)
+	// billing может читать любые ключи, но токен разрешает только payments/
+	narrow := &auth.Principal{Name: "billing", Rules: []auth.Rule{
+		{Verbs: []auth.Verb{auth.VerbGet, auth.VerbDelete}, Keys: []string{"payments/*"}},
+	}}
+
+	if err := policy.Authorize(narrow, auth.VerbGet, "default", "payments/1"); err != nil {
+		t.Errorf("expected read allowed by token and role, got %v", err)
+	}
+	if err := policy.Authorize(narrow, auth.VerbGet, "default", "orders/1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("expected token to narrow the reader role, got %v", err)
+	}
+	// Токен не расширяет права: удаление не разрешает ни одна роль billing
+	if err := policy.Authorize(narrow, auth.VerbDelete, "default", "payments/1"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("expected token not to widen the roles, got %v", err)
+	}
+}
+
+func TestPolicy_AuthorizeServer(t *testing.T) {
+	policy, err := auth.NewPolicy(auth.PolicyFile{
+		Roles: map[string][]auth.Rule{
+			"admin":          {{Verbs: []auth.Verb{auth.VerbAll}, Keys: []string{"*"}}},
+			"team-admin":     {{Verbs: []auth.Verb{auth.VerbAdmin}, Keys: []string{"*"}, Namespaces: []string{"?", "*"}}},
+			"payments-admin": {{Verbs: []auth.Verb{auth.VerbAdmin}, Keys: []string{"payments/*"}}},
+		},
+		Principals: map[string][]string{
+			"root":     {"admin"},
+			"team":     {"team-admin"},
+			"payments": {"payments-admin"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new policy: %v", err)
+	}
+
+	if err := policy.AuthorizeServer(&auth.Principal{Name: "root"}, auth.VerbAdmin); err != nil {
+		t.Errorf("admin without namespaces should manage the server: %v", err)
+	}
+	for _, name := range []string{"team", "payments"} {
+		if err := policy.AuthorizeServer(&auth.Principal{Name: name}, auth.VerbAdmin); !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("%s: expected ErrForbidden, got %v", name, err)
+		}
+	}
+}
+
+func TestNewPolicy_Validation(t *testing.T) {
+	_, err := auth.NewPolicy(auth.PolicyFile{
+		Roles: map[string][]auth.Rule{"bad": {{Verbs: []auth.Verb{"drop"}, Keys: []string{"*"}}}},
+	})
+	if err == nil {
+		t.Error("expected error for unknown verb")
+	}
+
+	_, err = auth.NewPolicy(auth.PolicyFile{Principals: map[string][]string{"billing": {"missing"}}})
+	if err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+func TestProxyHeader_TrustedNetworks(t *testing.T) {
+	proxy, err := auth.NewProxyHeader("X-Forwarded-User", []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("new proxy header: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/kv", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+
+	req.RemoteAddr = "10.1.2.3:4567"
+	principal, err := proxy.Authenticate(req)
+	if err != nil || principal.Name != "alice" {
+		t.Fatalf("expected alice from trusted proxy, got %v, %v", principal, err)
+	}
+
+	req.RemoteAddr = "192.168.1.1:4567"
+	if _, err := proxy.Authenticate(req); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials from untrusted address, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProxyHeader доверяет имени клиента из заголовка, который выставляет
+// прокси перед сервером. Заголовок принимается только от адресов прокси,
+// от остальных адресов запрос отклоняется.
+type ProxyHeader struct {
+	header  string
+	trusted []*net.IPNet
+}
+
+var _ Authenticator = (*ProxyHeader)(nil)
+
+// NewProxyHeader создает проверку заголовка header от прокси из сетей
+// trusted в нотации CIDR, например "10.0.0.0/8"
+func NewProxyHeader(header string, trusted []string) (*ProxyHeader, error) {
+	if header == "" {
+		return nil, errors.New("proxy header name is required")
+	}
+	if len(trusted) == 0 {
+		return nil, errors.New("at least one trusted proxy network is required")
+	}
+
+	p := &ProxyHeader{header: header}
+	for _, cidr := range trusted {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network: %w", err)
+		}
+		p.trusted = append(p.trusted, network)
+	}
+	return p, nil
+}
+
+func (p *ProxyHeader) Authenticate(r *http.Request) (*Principal, error) {
+	name := strings.TrimSpace(r.Header.Get(p.header))
+	if name == "" {
+		return nil, ErrNoCredentials
+	}
+	if !p.fromTrustedProxy(r) {
+		return nil, fmt.Errorf("%w: %s from untrusted address", ErrInvalidCredentials, p.header)
+	}
+	return &Principal{Name: name}, nil
+}
+
+func (p *ProxyHeader) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
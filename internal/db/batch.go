@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// MaxBatchSize - наибольшее число операций в одном пакете
+const MaxBatchSize = 1000
+
+// ErrBatchAborted возвращается для операций атомарного пакета,
+// изменения которых откатились из-за ошибки другой операции
+var ErrBatchAborted = errors.New("batch aborted")
+
+// BatchResult - результат одной операции пакета.
+// Для delete Item содержит удаленное значение.
+type BatchResult struct {
+	Item *models.KeyValue
+	Err  error
+}
+
+// BatchFailed сообщает, откатывает ли ошибка err операции op атомарный пакет.
+// Отсутствие ключа в get - обычный результат чтения, а не ошибка пакета.
+func BatchFailed(op models.BatchOperation, err error) bool {
+	if err == nil {
+		return false
+	}
+	return op.Op != models.BatchGet || !errors.Is(err, ErrNotFound)
+}
+
+// abortBatch помечает все успешные операции атомарного пакета как откатившиеся
+func abortBatch(results []BatchResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BatchResult{Err: ErrBatchAborted}
+		}
+	}
+}
+
+// validateBatchOp проверяет операцию до обращения к хранилищу
+func validateBatchOp(op models.BatchOperation) error {
+	switch op.Op {
+	case models.BatchGet, models.BatchCreate, models.BatchUpdate, models.BatchDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown batch operation %q", op.Op)
+	}
+}
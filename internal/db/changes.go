@@ -0,0 +1,22 @@
+package db
+
+import "errors"
+
+// ErrRevisionCompacted возвращается, если записи журнала после запрошенной
+// ревизии уже удалены компакцией и продолжить чтение без пропусков нельзя
+var ErrRevisionCompacted = errors.New("revision compacted")
+
+// ChangesOptions задает параметры чтения журнала изменений.
+// Since - последняя прочитанная ревизия, 0 для чтения с начала журнала.
+// С FromHead записи не читаются, а возвращается только текущая ревизия
+// журнала: с нее читает потребитель, который получил ключи листингом,
+// в том числе после ErrRevisionCompacted.
+type ChangesOptions struct {
+	Since    uint64
+	Limit    int
+	FromHead bool
+}
+
+func (o ChangesOptions) normalizedLimit() int {
+	return normalizeLimit(o.Limit)
+}
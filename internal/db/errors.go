@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	tarantool "github.com/tarantool/go-tarantool"
+)
+
+// Ошибки, которые возвращают все реализации Storage.
+// Проверять их нужно через errors.Is, а не по тексту сообщения.
+var (
+	ErrNotFound           = errors.New("key not found")
+	ErrAlreadyExists      = errors.New("key already exists")
+	ErrBackendUnavailable = errors.New("storage backend unavailable")
+	ErrVersionMismatch    = errors.New("version mismatch")
+)
+
+// tntErrVersionMismatch - код ошибки, которую init.lua выбрасывает,
+// если текущая версия ключа не совпала с ожидаемой
+const tntErrVersionMismatch = 10001
+
+// tntErrNamespaceNotFound - код ошибки, которую init.lua выбрасывает
+// при обращении к несуществующему пространству имен
+const tntErrNamespaceNotFound = 10002
+
+// wrapTarantoolError приводит ошибку go-tarantool к ошибкам пакета db.
+// Нарушение уникальности ключа превращается в ErrAlreadyExists,
+// несовпадение версии - в ErrVersionMismatch, отсутствие пространства
+// имен - в ErrNamespaceNotFound,
+// проблемы соединения - в ErrBackendUnavailable.
+func wrapTarantoolError(op string, err error) error {
+	var tntErr tarantool.Error
+	if errors.As(err, &tntErr) {
+		switch tntErr.Code {
+		case tarantool.ErrTupleFound:
+			return fmt.Errorf("%s: %w", op, ErrAlreadyExists)
+		case tntErrVersionMismatch:
+			return fmt.Errorf("%s: %w", op, ErrVersionMismatch)
+		case tntErrNamespaceNotFound:
+			return fmt.Errorf("%s: %w", op, ErrNamespaceNotFound)
+		}
+	}
+
+	var clientErr tarantool.ClientError
+	if errors.As(err, &clientErr) && clientErr.Code != tarantool.ErrProtocolError {
+		return fmt.Errorf("%s: %w: %w", op, ErrBackendUnavailable, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// expiresAtUnix возвращает время истечения ключа в секундах Unix,
+// 0 означает, что у ключа нет TTL
+func expiresAtUnix(in *models.KeyValue) int64 {
+	if in.ExpiresAt == nil {
+		return 0
+	}
+	return in.ExpiresAt.Unix()
+}
+
+// isExpired сообщает, истек ли ключ с временем истечения expiresAt к моменту now
+func isExpired(expiresAt int64, now time.Time) bool {
+	return expiresAt != 0 && expiresAt <= now.Unix()
+}
+
+// setExpiry заполняет время истечения и оставшийся TTL модели
+func setExpiry(item *models.KeyValue, expiresAt int64, now time.Time) {
+	if expiresAt == 0 {
+		item.ExpiresAt = nil
+		item.TTL = 0
+		return
+	}
+
+	t := time.Unix(expiresAt, 0).UTC()
+	item.ExpiresAt = &t
+	item.TTL = expiresAt - now.Unix()
+	if item.TTL < 0 {
+		item.TTL = 0
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultHistorySize - число хранимых версий каждого ключа по умолчанию
+const DefaultHistorySize = 10
+
+// ErrVersionNotFound возвращается, если запрошенной версии ключа нет в истории
+var ErrVersionNotFound = errors.New("version not found")
+
+// Restore записывает значение версии version ключа key как новую версию.
+// Ключ из корзины создается заново. Если ifVersion не равен нулю, запись
+// выполняется, только если текущая версия ключа равна ifVersion.
+// TTL восстановленное значение не получает.
+func Restore(ctx context.Context, storage Storage, key string, version, ifVersion uint64) (*models.KeyValue, error) {
+	old, err := storage.GetVersion(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, _, err := storage.Put(ctx, &models.KeyValue{Key: key, Value: old.Value}, PutUpsert, ifVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore version %d: %w", version, err)
+	}
+
+	log.LogInfo(ctx, "Key version restored", logrus.Fields{"key": key, "from": version, "version": restored.Version})
+	return restored, nil
+}
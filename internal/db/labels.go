@@ -0,0 +1,124 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// ErrInvalidSelector возвращается, если селектор меток не удалось разобрать
+var ErrInvalidSelector = errors.New("invalid label selector")
+
+// Операции требования селектора меток
+const (
+	labelEquals    = "="
+	labelNotEquals = "!="
+	labelExists    = "exists"
+	labelNotExists = "!exists"
+)
+
+// LabelRequirement - одно требование селектора к метке
+type LabelRequirement struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// LabelSelector - набор требований, которым должны удовлетворять все метки ключа.
+// Пустой селектор подходит к любому ключу.
+type LabelSelector []LabelRequirement
+
+// ParseLabelSelector разбирает селектор вида "team=payments,env!=prod,owner,!legacy":
+// требования через запятую, "k=v" и "k!=v" сравнивают значение метки,
+// "k" требует наличия метки, "!k" - ее отсутствия
+func ParseLabelSelector(raw string) (LabelSelector, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var selector LabelSelector
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		var req LabelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			key, value, _ := strings.Cut(part, "!=")
+			req = LabelRequirement{Key: strings.TrimSpace(key), Op: labelNotEquals, Value: strings.TrimSpace(value)}
+		case strings.Contains(part, "="):
+			key, value, _ := strings.Cut(part, "=")
+			req = LabelRequirement{Key: strings.TrimSpace(key), Op: labelEquals, Value: strings.TrimSpace(value)}
+		case strings.HasPrefix(part, "!"):
+			req = LabelRequirement{Key: strings.TrimSpace(part[1:]), Op: labelNotExists}
+		default:
+			req = LabelRequirement{Key: part, Op: labelExists}
+		}
+
+		if req.Key == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSelector, raw)
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// Matches сообщает, удовлетворяют ли метки всем требованиям селектора
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.Key]
+		switch req.Op {
+		case labelEquals:
+			if !ok || value != req.Value {
+				return false
+			}
+		case labelNotEquals:
+			if ok && value == req.Value {
+				return false
+			}
+		case labelExists:
+			if !ok {
+				return false
+			}
+		case labelNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// args представляет селектор аргументом list_kv: списком {key, op, value}
+func (s LabelSelector) args() []interface{} {
+	args := make([]interface{}, 0, len(s))
+	for _, req := range s {
+		args = append(args, []interface{}{req.Key, req.Op, req.Value})
+	}
+	return args
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	out := make(map[string]string, len(labels))
+	for key, value := range labels {
+		out[key] = value
+	}
+	return out
+}
+
+// setTimestamps заполняет время создания и изменения модели.
+// Нулевое время означает, что ключ записан до появления этих полей.
+func setTimestamps(item *models.KeyValue, createdAt, updatedAt time.Time) {
+	if !createdAt.IsZero() {
+		t := createdAt.UTC()
+		item.CreatedAt = &t
+	}
+	if !updatedAt.IsZero() {
+		t := updatedAt.UTC()
+		item.UpdatedAt = &t
+	}
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultListLimit - размер страницы листинга, если лимит не задан
+	DefaultListLimit = 100
+	// MaxListLimit - максимальный размер страницы листинга
+	MaxListLimit = 1000
+)
+
+// ErrInvalidCursor возвращается, если курсор листинга не удалось разобрать
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListOptions задает параметры листинга ключей.
+// Cursor - непрозрачное значение из предыдущей страницы, пустое для первой.
+type ListOptions struct {
+	Prefix string
+	Limit  int
+	Cursor string
+	// Labels оставляет в листинге только ключи с подходящими метками
+	Labels LabelSelector
+}
+
+func (o ListOptions) normalizedLimit() int {
+	return normalizeLimit(o.Limit)
+}
+
+// normalizeLimit приводит размер страницы к диапазону [1, MaxListLimit]
+func normalizeLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultListLimit
+	case limit > MaxListLimit:
+		return MaxListLimit
+	default:
+		return limit
+	}
+}
+
+// encodeCursor кодирует последний ключ страницы в курсор
+func encodeCursor(lastKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
+
+// decodeCursor возвращает ключ, после которого начинается следующая страница
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) == 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return string(raw), nil
+}
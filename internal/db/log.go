@@ -0,0 +1,6 @@
+package db
+
+import "github.com/MosinFAM/tarantool-kv/internal/logger"
+
+// log пишет логи хранилищ с уровнем пакета "db", см. logger.SetPackageLevel
+var log = logger.Package("db")
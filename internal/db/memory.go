@@ -0,0 +1,519 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryStorage хранит пары ключ-значение в памяти процесса.
+// Значения хранятся сериализованными, как и в Tarantool, поэтому
+// вызывающий код не может изменить сохраненные данные по ссылке.
+// Истекшие ключи сразу становятся невидимыми и удаляются при следующей записи
+// или фоновой очисткой (см. WithExpireInterval) с событием delete в журнале
+// изменений.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	items   map[string]memoryItem
+	log     memoryChangelog
+	history map[string][]memoryItem
+	// historySize - число хранимых версий каждого ключа
+	historySize int
+	trash       map[string]memoryTrashed
+	// trashRetention - срок хранения удаленных ключей в корзине,
+	// 0 означает, что ключи удаляются безвозвратно
+	trashRetention time.Duration
+	// trashPurgedAt - время последней очистки корзины
+	trashPurgedAt time.Time
+	// expireInterval - период фоновой очистки, 0 отключает ее
+	expireInterval time.Duration
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
+type memoryItem struct {
+	value     []byte
+	version   uint64
+	expiresAt int64
+	createdAt time.Time
+	updatedAt time.Time
+	// labels не изменяются после записи, поэтому элемент можно копировать
+	labels map[string]string
+}
+
+// newMemoryItem сериализует значение и копирует метки из запроса записи
+func newMemoryItem(in *models.KeyValue) (memoryItem, error) {
+	dataSerialized, err := json.Marshal(in.Value)
+	if err != nil {
+		return memoryItem{}, fmt.Errorf("data serialization failed: %w", err)
+	}
+	return memoryItem{value: dataSerialized, expiresAt: expiresAtUnix(in), labels: copyLabels(in.Labels)}, nil
+}
+
+// created возвращает первую версию нового ключа
+func (i memoryItem) created(now time.Time) memoryItem {
+	i.version = 1
+	i.createdAt = now
+	i.updatedAt = now
+	return i
+}
+
+// updated возвращает следующую версию ключа current
+func (i memoryItem) updated(current memoryItem, now time.Time) memoryItem {
+	i.version = current.version + 1
+	i.createdAt = current.createdAt
+	i.updatedAt = now
+	if i.labels == nil {
+		i.labels = current.labels
+	}
+	return i
+}
+
+// alive сообщает, что элемент существует и его TTL не истек
+func (i memoryItem) alive(ok bool) bool {
+	return ok && !isExpired(i.expiresAt, time.Now())
+}
+
+var _ Storage = (*MemoryStorage)(nil)
+
+// MemoryOption настраивает MemoryStorage при создании
+type MemoryOption func(*MemoryStorage)
+
+// WithHistorySize задает число хранимых версий каждого ключа, 0 отключает историю
+func WithHistorySize(size int) MemoryOption {
+	return func(m *MemoryStorage) {
+		m.historySize = size
+	}
+}
+
+// WithTrashRetention включает мягкое удаление: удаленные ключи хранятся
+// в корзине в течение retention и могут быть восстановлены
+func WithTrashRetention(retention time.Duration) MemoryOption {
+	return func(m *MemoryStorage) {
+		m.trashRetention = retention
+	}
+}
+
+// WithExpireInterval включает фоновую очистку раз в interval, как
+// KV_EXPIRE_INTERVAL в Tarantool: удаление истекших ключей и ключей корзины
+// с истекшим сроком хранения. Без нее истекший ключ остается в журнале
+// изменений живым до следующей записи в него, а корзина очищается только
+// при удалении ключей и обращениях к ней. Фоновую очистку останавливает Close.
+func WithExpireInterval(interval time.Duration) MemoryOption {
+	return func(m *MemoryStorage) {
+		m.expireInterval = interval
+	}
+}
+
+func NewMemoryStorage(opts ...MemoryOption) *MemoryStorage {
+	m := &MemoryStorage{
+		items:       make(map[string]memoryItem),
+		history:     make(map[string][]memoryItem),
+		historySize: DefaultHistorySize,
+		trash:       make(map[string]memoryTrashed),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.expireInterval > 0 {
+		m.stop = make(chan struct{})
+		go m.expireLoop()
+	}
+	return m
+}
+
+// Close останавливает фоновую очистку. Данные остаются доступными.
+func (m *MemoryStorage) Close() {
+	if m.stop != nil {
+		m.stopOnce.Do(func() { close(m.stop) })
+	}
+}
+
+func (m *MemoryStorage) expireLoop() {
+	ticker := time.NewTicker(m.expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.sweep(now)
+		}
+	}
+}
+
+// sweep удаляет истекшие к now ключи и очищает корзину
+func (m *MemoryStorage) sweep(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, item := range m.items {
+		if isExpired(item.expiresAt, now) {
+			m.expireLocked(key)
+		}
+	}
+	if m.trashRetention > 0 {
+		m.purgeTrashLocked(now.UTC())
+	}
+}
+
+// Create добавляет новую пару ключ-значение
+func (m *MemoryStorage) Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to insert key: %w", err)
+	}
+
+	write, err := newMemoryItem(in)
+	if err != nil {
+		log.LogError(ctx, "Data serialization failed", err, logrus.Fields{"key": in.Key})
+		return nil, err
+	}
+
+	m.mu.Lock()
+	item, err := m.insertLocked(in.Key, write)
+	m.mu.Unlock()
+	if err != nil {
+		log.LogInfo(ctx, "Key already exists during insert", logrus.Fields{"key": in.Key})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully created", logrus.Fields{"key": in.Key})
+	return decodeMemoryItem(in.Key, item)
+}
+
+// Get получает значение по ключу
+func (m *MemoryStorage) Get(ctx context.Context, key string) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	m.mu.RLock()
+	item, err := m.getLocked(key)
+	m.mu.RUnlock()
+	if err != nil {
+		log.LogInfo(ctx, "Key not found", logrus.Fields{"key": key})
+		return nil, err
+	}
+
+	return decodeMemoryItem(key, item)
+}
+
+// Delete удаляет ключ и возвращает удаленное значение
+func (m *MemoryStorage) Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete key: %w", err)
+	}
+
+	m.mu.Lock()
+	item, err := m.deleteLocked(key, ifVersion)
+	if err == nil {
+		m.purgeTrashDueLocked(time.Now().UTC())
+	}
+	m.mu.Unlock()
+	if err != nil {
+		log.LogInfo(ctx, "Failed to delete key", logrus.Fields{"key": key, "error": err.Error()})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully deleted", logrus.Fields{"key": key})
+	return decodeMemoryItem(key, item)
+}
+
+// Update обновляет значение для существующего ключа и увеличивает его версию
+func (m *MemoryStorage) Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update key: %w", err)
+	}
+
+	write, err := newMemoryItem(in)
+	if err != nil {
+		log.LogError(ctx, "Data serialization failed during update", err, logrus.Fields{"key": in.Key})
+		return nil, err
+	}
+
+	m.mu.Lock()
+	item, err := m.updateLocked(in.Key, write, ifVersion)
+	m.mu.Unlock()
+	if err != nil {
+		log.LogInfo(ctx, "Failed to update key", logrus.Fields{"key": in.Key, "error": err.Error()})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully updated", logrus.Fields{"key": in.Key})
+	return decodeMemoryItem(in.Key, item)
+}
+
+// Put записывает значение в режиме mode и сообщает, был ли ключ создан
+func (m *MemoryStorage) Put(ctx context.Context, in *models.KeyValue, mode PutMode, ifVersion uint64) (*models.KeyValue, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to put key: %w", err)
+	}
+
+	write, err := newMemoryItem(in)
+	if err != nil {
+		log.LogError(ctx, "Data serialization failed during put", err, logrus.Fields{"key": in.Key})
+		return nil, false, err
+	}
+
+	m.mu.Lock()
+	item, created, err := m.putLocked(in.Key, write, mode, ifVersion)
+	m.mu.Unlock()
+	if err != nil {
+		log.LogInfo(ctx, "Failed to put key", logrus.Fields{"key": in.Key, "mode": mode, "error": err.Error()})
+		return nil, false, err
+	}
+
+	log.LogInfo(ctx, "Key successfully put", logrus.Fields{"key": in.Key, "mode": mode, "created": created})
+	kv, err := decodeMemoryItem(in.Key, item)
+	return kv, created, err
+}
+
+// Batch выполняет операции пакета последовательно под одной блокировкой.
+// В атомарном режиме при первой ошибке все изменения пакета откатываются,
+// см. BatchFailed.
+func (m *MemoryStorage) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to run batch: %w", err)
+	}
+
+	results := make([]BatchResult, len(ops))
+	// undo хранит исходное состояние измененных ключей
+	undo := make(map[string]memoryUndo)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	revision := m.log.revision
+
+	for i, op := range ops {
+		if _, saved := undo[op.Key]; !saved && op.Op != models.BatchGet {
+			previous := memoryUndo{history: m.history[op.Key]}
+			if current, ok := m.items[op.Key]; ok {
+				previous.item = &current
+			}
+			if trashed, ok := m.trash[op.Key]; ok {
+				previous.trashed = &trashed
+			}
+			undo[op.Key] = previous
+		}
+
+		item, err := m.batchOpLocked(op)
+		if err != nil {
+			results[i] = BatchResult{Err: err}
+			if atomic && BatchFailed(op, err) {
+				for key, previous := range undo {
+					if previous.item == nil {
+						delete(m.items, key)
+					} else {
+						m.items[key] = *previous.item
+					}
+					if previous.history == nil {
+						delete(m.history, key)
+					} else {
+						m.history[key] = previous.history
+					}
+					if previous.trashed == nil {
+						delete(m.trash, key)
+					} else {
+						m.trash[key] = *previous.trashed
+					}
+				}
+				m.log.rollback(revision)
+				abortBatch(results)
+				log.LogInfo(ctx, "Batch rolled back", logrus.Fields{"operation": i, "key": op.Key})
+				return results, nil
+			}
+			continue
+		}
+
+		results[i].Item, results[i].Err = decodeMemoryItem(op.Key, item)
+	}
+	// Корзина очищается только после пакета: откат не восстановил бы ее
+	m.purgeTrashDueLocked(time.Now().UTC())
+
+	log.LogInfo(ctx, "Batch successfully executed", logrus.Fields{"operations": len(ops), "atomic": atomic})
+	return results, nil
+}
+
+func (m *MemoryStorage) batchOpLocked(op models.BatchOperation) (memoryItem, error) {
+	if err := validateBatchOp(op); err != nil {
+		return memoryItem{}, err
+	}
+
+	switch op.Op {
+	case models.BatchGet:
+		return m.getLocked(op.Key)
+	case models.BatchDelete:
+		return m.deleteLocked(op.Key, op.IfVersion)
+	}
+
+	write, err := newMemoryItem(&models.KeyValue{Value: op.Value, ExpiresAt: op.ExpiresAt, Labels: op.Labels})
+	if err != nil {
+		return memoryItem{}, err
+	}
+	if op.Op == models.BatchCreate {
+		return m.insertLocked(op.Key, write)
+	}
+	return m.updateLocked(op.Key, write, op.IfVersion)
+}
+
+func (m *MemoryStorage) getLocked(key string) (memoryItem, error) {
+	item, ok := m.items[key]
+	if !item.alive(ok) {
+		return memoryItem{}, ErrNotFound
+	}
+	return item, nil
+}
+
+// expireLocked удаляет истекший ключ и записывает удаление в журнал,
+// как фоновое удаление истекших ключей в Tarantool
+func (m *MemoryStorage) expireLocked(key string) {
+	item, ok := m.items[key]
+	if !ok || item.alive(ok) {
+		return
+	}
+	delete(m.items, key)
+	delete(m.history, key)
+	m.recordLocked(models.EventDelete, key, item)
+}
+
+// insertLocked создает ключ со значением, TTL и метками из write
+func (m *MemoryStorage) insertLocked(key string, write memoryItem) (memoryItem, error) {
+	m.expireLocked(key)
+	if current, ok := m.items[key]; current.alive(ok) {
+		return memoryItem{}, ErrAlreadyExists
+	}
+	item := write.created(time.Now().UTC())
+	m.items[key] = item
+	m.recordLocked(models.EventCreate, key, item)
+	return item, nil
+}
+
+// updateLocked заменяет значение и TTL ключа значениями из write.
+// Метки заменяются, только если они переданы.
+func (m *MemoryStorage) updateLocked(key string, write memoryItem, ifVersion uint64) (memoryItem, error) {
+	m.expireLocked(key)
+	current, ok := m.items[key]
+	if !current.alive(ok) {
+		return memoryItem{}, ErrNotFound
+	}
+	if !versionMatches(current.version, ifVersion) {
+		return memoryItem{}, fmt.Errorf("failed to update key: %w", ErrVersionMismatch)
+	}
+	item := write.updated(current, time.Now().UTC())
+	m.items[key] = item
+	m.recordLocked(models.EventUpdate, key, item)
+	return item, nil
+}
+
+func (m *MemoryStorage) putLocked(key string, write memoryItem, mode PutMode, ifVersion uint64) (memoryItem, bool, error) {
+	if !mode.Valid() {
+		return memoryItem{}, false, fmt.Errorf("unknown put mode %q", mode)
+	}
+
+	m.expireLocked(key)
+	current, ok := m.items[key]
+	if current.alive(ok) {
+		if mode == PutCreate {
+			return memoryItem{}, false, ErrAlreadyExists
+		}
+		item, err := m.updateLocked(key, write, ifVersion)
+		return item, false, err
+	}
+
+	if mode == PutReplace {
+		return memoryItem{}, false, ErrNotFound
+	}
+	if ifVersion != 0 {
+		return memoryItem{}, false, fmt.Errorf("failed to put key: %w", ErrVersionMismatch)
+	}
+	item := write.created(time.Now().UTC())
+	m.items[key] = item
+	m.recordLocked(models.EventCreate, key, item)
+	return item, true, nil
+}
+
+func (m *MemoryStorage) deleteLocked(key string, ifVersion uint64) (memoryItem, error) {
+	m.expireLocked(key)
+	item, ok := m.items[key]
+	if !item.alive(ok) {
+		return memoryItem{}, ErrNotFound
+	}
+	if !versionMatches(item.version, ifVersion) {
+		return memoryItem{}, fmt.Errorf("failed to delete key: %w", ErrVersionMismatch)
+	}
+	delete(m.items, key)
+	// История нужна, пока ключ можно восстановить из корзины
+	if m.trashRetention > 0 {
+		m.trash[key] = memoryTrashed{item: item, deletedAt: time.Now().UTC()}
+	} else {
+		delete(m.history, key)
+	}
+	m.recordLocked(models.EventDelete, key, item)
+	return item, nil
+}
+
+// List возвращает страницу ключей с заданным префиксом в порядке возрастания
+func (m *MemoryStorage) List(ctx context.Context, opts ListOptions) ([]*models.KeyValue, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	after, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		log.LogInfo(ctx, "Invalid list cursor", logrus.Fields{"cursor": opts.Cursor})
+		return nil, "", err
+	}
+	limit := opts.normalizedLimit()
+
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.items))
+	for key, item := range m.items {
+		if strings.HasPrefix(key, opts.Prefix) && key > after && item.alive(true) && opts.Labels.Matches(item.labels) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	nextCursor := ""
+	if len(keys) > limit {
+		keys = keys[:limit]
+		nextCursor = encodeCursor(keys[limit-1])
+	}
+
+	snapshot := make([]memoryItem, len(keys))
+	for i, key := range keys {
+		snapshot[i] = m.items[key]
+	}
+	m.mu.RUnlock()
+
+	items := make([]*models.KeyValue, 0, len(keys))
+	for i, key := range keys {
+		item, err := decodeMemoryItem(key, snapshot[i])
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+	}
+
+	return items, nextCursor, nil
+}
+
+func decodeMemoryItem(key string, item memoryItem) (*models.KeyValue, error) {
+	var value map[string]interface{}
+	if err := json.Unmarshal(item.value, &value); err != nil {
+		log.LogError(context.Background(), "Failed to unmarshal value", err, logrus.Fields{"key": key})
+		return nil, fmt.Errorf("failed to deserialize value: %w", err)
+	}
+
+	kv := &models.KeyValue{Key: key, Value: value, Version: item.version, Labels: copyLabels(item.labels)}
+	setExpiry(kv, item.expiresAt, time.Now())
+	setTimestamps(kv, item.createdAt, item.updatedAt)
+	return kv, nil
+}
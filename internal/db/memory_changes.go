@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryChangelogSize - число последних записей журнала, которые хранит
+// MemoryStorage. Более старые записи удаляются, когда их накопится
+// memoryChangelogTrim, чтобы не сдвигать журнал при каждой записи.
+const (
+	memoryChangelogSize = 10000
+	memoryChangelogTrim = memoryChangelogSize / 4
+)
+
+// memoryChangelog - журнал изменений MemoryStorage, защищенный ее мьютексом
+type memoryChangelog struct {
+	entries []memoryChange
+	// revision - ревизия последней записи
+	revision uint64
+	// compacted - ревизия последней удаленной компакцией записи
+	compacted uint64
+}
+
+type memoryChange struct {
+	revision  uint64
+	eventType string
+	key       string
+	item      memoryItem
+	timestamp time.Time
+}
+
+func (l *memoryChangelog) append(eventType, key string, item memoryItem) {
+	l.revision++
+	l.entries = append(l.entries, memoryChange{
+		revision:  l.revision,
+		eventType: eventType,
+		key:       key,
+		item:      item,
+		timestamp: time.Now().UTC(),
+	})
+
+	if excess := len(l.entries) - memoryChangelogSize; excess >= memoryChangelogTrim {
+		l.compacted = l.entries[excess-1].revision
+		n := copy(l.entries, l.entries[excess:])
+		// Удаленные записи не должны удерживать значения ключей
+		clear(l.entries[n:])
+		l.entries = l.entries[:n]
+	}
+}
+
+// rollback удаляет записи, добавленные после ревизии revision
+func (l *memoryChangelog) rollback(revision uint64) {
+	i := sort.Search(len(l.entries), func(i int) bool { return l.entries[i].revision > revision })
+	l.entries = l.entries[:i]
+	l.revision = revision
+}
+
+// Changes возвращает записи журнала изменений после opts.Since.
+// Если журнал прочитан до конца, чтение продолжается с его последней ревизии.
+func (m *MemoryStorage) Changes(ctx context.Context, opts ChangesOptions) ([]*models.Change, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read changes: %w", err)
+	}
+	limit := opts.normalizedLimit()
+
+	m.mu.RLock()
+	if opts.FromHead {
+		head := m.log.revision
+		m.mu.RUnlock()
+		return nil, head, nil
+	}
+	if opts.Since < m.log.compacted {
+		m.mu.RUnlock()
+		log.LogInfo(ctx, "Changes since compacted revision requested", logrus.Fields{"since": opts.Since})
+		return nil, 0, fmt.Errorf("failed to read changes since %d: %w", opts.Since, ErrRevisionCompacted)
+	}
+
+	entries := m.log.entries
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].revision > opts.Since })
+	end := min(start+limit, len(entries))
+	snapshot := make([]memoryChange, end-start)
+	copy(snapshot, entries[start:end])
+	revision := max(opts.Since, m.log.revision)
+	if end < len(entries) {
+		revision = entries[end-1].revision
+	}
+	m.mu.RUnlock()
+
+	changes := make([]*models.Change, 0, len(snapshot))
+	for _, entry := range snapshot {
+		kv, err := decodeMemoryItem(entry.key, entry.item)
+		if err != nil {
+			return nil, 0, err
+		}
+		changes = append(changes, &models.Change{
+			Revision: entry.revision,
+			Event: models.Event{
+				Type:    entry.eventType,
+				Key:     entry.key,
+				Version: kv.Version,
+				Value:   kv.Value,
+			},
+			Timestamp: entry.timestamp,
+		})
+	}
+	return changes, revision, nil
+}
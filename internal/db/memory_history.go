@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// memoryUndo - состояние ключа до начала пакета
+type memoryUndo struct {
+	// item равен nil, если ключа не было
+	item    *memoryItem
+	history []memoryItem
+	// trashed равен nil, если ключа не было в корзине
+	trashed *memoryTrashed
+}
+
+// recordLocked добавляет запись в журнал изменений и в историю ключа.
+// Ключ, созданный с первой версией, начинает историю заново: версии прежнего
+// ключа с тем же именем нумеровались бы так же. Ключ, восстановленный
+// из корзины, продолжает нумерацию и сохраняет историю.
+func (m *MemoryStorage) recordLocked(eventType, key string, item memoryItem) {
+	m.log.append(eventType, key, item)
+
+	if eventType == models.EventDelete || m.historySize <= 0 {
+		return
+	}
+	history := m.history[key]
+	if eventType == models.EventCreate && item.version == 1 {
+		history = nil
+	}
+
+	// История не изменяется на месте: пакет хранит прежние срезы для отката
+	// TTL относится к ключу, а не к версии
+	item.expiresAt = 0
+	history = append(history[:len(history):len(history)], item)
+	if excess := len(history) - m.historySize; excess > 0 {
+		history = append([]memoryItem(nil), history[excess:]...)
+	}
+	m.history[key] = history
+}
+
+// History возвращает сохраненные версии ключа, начиная с последней
+func (m *MemoryStorage) History(ctx context.Context, key string) ([]*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	m.mu.RLock()
+	history := m.history[key]
+	m.mu.RUnlock()
+
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+
+	items := make([]*models.KeyValue, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		item, err := decodeMemoryItem(key, history[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetVersion возвращает сохраненную версию ключа
+func (m *MemoryStorage) GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	m.mu.RLock()
+	history := m.history[key]
+	m.mu.RUnlock()
+
+	for _, entry := range history {
+		if entry.version == version {
+			return decodeMemoryItem(key, entry)
+		}
+	}
+	return nil, fmt.Errorf("failed to get version %d of key %q: %w", version, key, ErrVersionNotFound)
+}
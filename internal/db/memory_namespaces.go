@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryNamespaces хранит каждое пространство имен в отдельном MemoryStorage.
+// Ревизии журнала изменений у каждого пространства свои.
+type MemoryNamespaces struct {
+	mu     sync.RWMutex
+	spaces map[string]memoryNamespace
+	opts   []MemoryOption
+}
+
+type memoryNamespace struct {
+	storage   *MemoryStorage
+	createdAt time.Time
+}
+
+var _ Namespaces = (*MemoryNamespaces)(nil)
+
+// NewMemoryNamespaces создает набор пространств имен с пространством
+// DefaultNamespace. Параметры opts применяются к хранилищу каждого пространства.
+func NewMemoryNamespaces(opts ...MemoryOption) *MemoryNamespaces {
+	n := &MemoryNamespaces{spaces: make(map[string]memoryNamespace), opts: opts}
+	n.spaces[DefaultNamespace] = memoryNamespace{storage: NewMemoryStorage(opts...), createdAt: time.Now()}
+	return n
+}
+
+func (n *MemoryNamespaces) Namespace(ctx context.Context, name string) (Storage, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	space, ok := n.spaces[name]
+	if !ok {
+		return nil, fmt.Errorf("failed to open namespace %q: %w", name, ErrNamespaceNotFound)
+	}
+	return space.storage, nil
+}
+
+func (n *MemoryNamespaces) CreateNamespace(ctx context.Context, name string) (*models.Namespace, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create namespace: %w", err)
+	}
+	if err := ValidateNamespace(name); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.spaces[name]; ok {
+		log.LogInfo(ctx, "Namespace already exists", logrus.Fields{"namespace": name})
+		return nil, fmt.Errorf("failed to create namespace %q: %w", name, ErrNamespaceExists)
+	}
+	space := memoryNamespace{storage: NewMemoryStorage(n.opts...), createdAt: time.Now()}
+	n.spaces[name] = space
+
+	log.LogInfo(ctx, "Namespace successfully created", logrus.Fields{"namespace": name})
+	return &models.Namespace{Name: name, CreatedAt: space.createdAt.UTC()}, nil
+}
+
+func (n *MemoryNamespaces) ListNamespaces(ctx context.Context) ([]*models.Namespace, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	namespaces := make([]*models.Namespace, 0, len(n.spaces))
+	for name, space := range n.spaces {
+		namespaces = append(namespaces, &models.Namespace{Name: name, CreatedAt: space.createdAt.UTC()})
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces, nil
+}
+
+func (n *MemoryNamespaces) DeleteNamespace(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete namespace: %w", err)
+	}
+	if name == DefaultNamespace {
+		return ErrDefaultNamespace
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.spaces[name]; !ok {
+		return fmt.Errorf("failed to delete namespace %q: %w", name, ErrNamespaceNotFound)
+	}
+	n.spaces[name].storage.Close()
+	delete(n.spaces, name)
+
+	log.LogInfo(ctx, "Namespace successfully deleted", logrus.Fields{"namespace": name})
+	return nil
+}
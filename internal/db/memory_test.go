@@ -0,0 +1,788 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+func setupMemory(t *testing.T) *db.MemoryStorage {
+	t.Helper()
+	logger.Init()
+	return db.NewMemoryStorage()
+}
+
+func TestMemoryStorage_CRUD(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	in := &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "testValue"}}
+	if _, err := s.Create(ctx, in); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, err := s.Get(ctx, "testKey")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Value["data"] != "testValue" {
+		t.Errorf("expected 'testValue', got %v", got.Value["data"])
+	}
+
+	if _, err := s.Update(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "newValue"}}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	deleted, err := s.Delete(ctx, "testKey", 0)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if deleted.Value["data"] != "newValue" {
+		t.Errorf("expected deleted value 'newValue', got %v", deleted.Value["data"])
+	}
+
+	if _, err := s.Get(ctx, "testKey"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected 'key not found', got %v", err)
+	}
+}
+
+func TestMemoryStorage_Errors(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	in := &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "testValue"}}
+	if _, err := s.Create(ctx, in); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Create(ctx, in); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Errorf("expected 'key already exists', got %v", err)
+	}
+
+	missing := &models.KeyValue{Key: "missingKey", Value: map[string]interface{}{"data": "testValue"}}
+	if _, err := s.Update(ctx, missing, 0); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected 'key not found' on update, got %v", err)
+	}
+	if _, err := s.Delete(ctx, "missingKey", 0); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected 'key not found' on delete, got %v", err)
+	}
+}
+
+func TestMemoryStorage_ValueIsCopied(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	value := map[string]interface{}{"data": "testValue"}
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "testKey", Value: value}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	value["data"] = "mutated"
+
+	got, err := s.Get(ctx, "testKey")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Value["data"] != "testValue" {
+		t.Errorf("stored value was mutated through caller's map: %v", got.Value["data"])
+	}
+}
+
+func TestMemoryStorage_ConcurrentCreate(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	const workers = 16
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Create(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "testValue"}})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("expected exactly one successful create, got %d", created)
+	}
+}
+
+func TestMemoryStorage_CanceledContext(t *testing.T) {
+	s := setupMemory(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Create(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "testValue"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMemoryStorage_ListPagination(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"app/c", "app/a", "other/x", "app/b", "app"} {
+		if _, err := s.Create(ctx, &models.KeyValue{Key: key, Value: map[string]interface{}{"data": key}}); err != nil {
+			t.Fatalf("create %s: %v", key, err)
+		}
+	}
+
+	var keys []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+		items, next, err := s.List(ctx, db.ListOptions{Prefix: "app/", Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		for _, item := range items {
+			keys = append(keys, item.Key)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []string{"app/a", "app/b", "app/c"}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected keys %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("expected keys %v, got %v", expected, keys)
+			break
+		}
+	}
+}
+
+func TestMemoryStorage_ListInvalidCursor(t *testing.T) {
+	s := setupMemory(t)
+
+	_, _, err := s.List(context.Background(), db.ListOptions{Cursor: "%%%"})
+	if !errors.Is(err, db.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestMemoryStorage_Versions(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "v1"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Version != 1 {
+		t.Errorf("expected version 1 after create, got %d", created.Version)
+	}
+
+	updated, err := s.Update(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "v2"}}, 1)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("expected version 2 after update, got %d", updated.Version)
+	}
+
+	// Запись со старой версией должна быть отклонена
+	if _, err := s.Update(ctx, &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "stale"}}, 1); !errors.Is(err, db.ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch on update, got %v", err)
+	}
+	if _, err := s.Delete(ctx, "testKey", 1); !errors.Is(err, db.ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch on delete, got %v", err)
+	}
+	if _, err := s.Delete(ctx, "testKey", 2); err != nil {
+		t.Errorf("delete with current version: %v", err)
+	}
+}
+
+func TestMemoryStorage_ExpireIntervalRecordsDelete(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithExpireInterval(10 * time.Millisecond))
+	defer s.Close()
+	ctx := context.Background()
+
+	soon := time.Now().Add(20 * time.Millisecond)
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "session", Value: map[string]interface{}{"user": "1"}, ExpiresAt: &soon}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Удаление попадает в журнал без записей в ключ
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		changes, _, err := s.Changes(ctx, db.ChangesOptions{})
+		if err != nil {
+			t.Fatalf("changes: %v", err)
+		}
+		if len(changes) == 2 {
+			if changes[1].Type != models.EventDelete || changes[1].Key != "session" {
+				t.Errorf("expected delete of session, got %+v", changes[1])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected expired key to be deleted, got %d changes", len(changes))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryStorage_ExpiredKeyIsInvisible(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Second)
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "session", Value: map[string]interface{}{"user": "1"}, ExpiresAt: &past}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := s.Get(ctx, "session"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for expired key, got %v", err)
+	}
+	items, _, err := s.List(ctx, db.ListOptions{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected expired key to be hidden from listing, got %d items", len(items))
+	}
+
+	// Истекший ключ можно создать заново
+	future := time.Now().Add(time.Hour)
+	created, err := s.Create(ctx, &models.KeyValue{Key: "session", Value: map[string]interface{}{"user": "2"}, ExpiresAt: &future})
+	if err != nil {
+		t.Fatalf("create over expired key: %v", err)
+	}
+	if created.TTL <= 0 || created.TTL > 3600 {
+		t.Errorf("expected remaining TTL in (0, 3600], got %d", created.TTL)
+	}
+
+	changes, _, err := s.Changes(ctx, db.ChangesOptions{})
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	want := []string{models.EventCreate, models.EventDelete, models.EventCreate}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d", len(want), len(changes))
+	}
+	for i, change := range changes {
+		if change.Type != want[i] {
+			t.Errorf("change %d: expected %s, got %s", i, want[i], change.Type)
+		}
+	}
+}
+
+func TestMemoryStorage_BatchAtomicRollback(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	results, err := s.Batch(ctx, []models.BatchOperation{
+		{Op: models.BatchUpdate, Key: "a", Value: map[string]interface{}{"n": 2.0}},
+		{Op: models.BatchCreate, Key: "b", Value: map[string]interface{}{"n": 1.0}},
+		{Op: models.BatchCreate, Key: "a", Value: map[string]interface{}{"n": 3.0}},
+	}, true)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+
+	if !errors.Is(results[0].Err, db.ErrBatchAborted) || !errors.Is(results[1].Err, db.ErrBatchAborted) {
+		t.Errorf("expected aborted operations, got %v, %v", results[0].Err, results[1].Err)
+	}
+	if !errors.Is(results[2].Err, db.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists, got %v", results[2].Err)
+	}
+
+	got, err := s.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Value["n"] != 1.0 || got.Version != 1 {
+		t.Errorf("expected key to be rolled back, got %v version %d", got.Value, got.Version)
+	}
+	if _, err := s.Get(ctx, "b"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected created key to be rolled back, got %v", err)
+	}
+}
+
+func TestMemoryStorage_BatchNonAtomic(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	results, err := s.Batch(ctx, []models.BatchOperation{
+		{Op: models.BatchCreate, Key: "a", Value: map[string]interface{}{"n": 1.0}},
+		{Op: models.BatchDelete, Key: "missing"},
+		{Op: models.BatchGet, Key: "a"},
+	}, false)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+
+	if results[0].Err != nil || results[0].Item.Version != 1 {
+		t.Errorf("expected created key, got %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, db.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Item.Value["n"] != 1.0 {
+		t.Errorf("expected to read created key, got %+v", results[2])
+	}
+}
+
+func TestMemoryStorage_BatchAtomicGetMiss(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	results, err := s.Batch(ctx, []models.BatchOperation{
+		{Op: models.BatchGet, Key: "missing"},
+		{Op: models.BatchCreate, Key: "a", Value: map[string]interface{}{"n": 1.0}},
+	}, true)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+
+	if !errors.Is(results[0].Err, db.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", results[0].Err)
+	}
+	if results[1].Err != nil {
+		t.Errorf("expected create to be committed, got %v", results[1].Err)
+	}
+	if _, err := s.Get(ctx, "a"); err != nil {
+		t.Errorf("expected created key, got %v", err)
+	}
+}
+
+func TestMemoryStorage_PutModes(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+	in := &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "v1"}}
+
+	if _, _, err := s.Put(ctx, in, db.PutReplace, 0); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("replace of missing key: expected ErrNotFound, got %v", err)
+	}
+
+	item, created, err := s.Put(ctx, in, db.PutUpsert, 0)
+	if err != nil || !created || item.Version != 1 {
+		t.Fatalf("upsert of missing key: got %+v, created %v, err %v", item, created, err)
+	}
+
+	if _, _, err := s.Put(ctx, in, db.PutCreate, 0); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Errorf("create of existing key: expected ErrAlreadyExists, got %v", err)
+	}
+
+	item, created, err = s.Put(ctx, in, db.PutUpsert, 1)
+	if err != nil || created || item.Version != 2 {
+		t.Errorf("upsert of existing key: got %+v, created %v, err %v", item, created, err)
+	}
+
+	if _, _, err := s.Put(ctx, in, db.PutReplace, 1); !errors.Is(err, db.ErrVersionMismatch) {
+		t.Errorf("replace with stale version: expected ErrVersionMismatch, got %v", err)
+	}
+}
+
+func TestMemoryStorage_Changes(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Update(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 2.0}}, 0); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	// Откатившийся пакет не должен попасть в журнал
+	if _, err := s.Batch(ctx, []models.BatchOperation{
+		{Op: models.BatchCreate, Key: "b", Value: map[string]interface{}{"n": 1.0}},
+		{Op: models.BatchCreate, Key: "a", Value: map[string]interface{}{"n": 1.0}},
+	}, true); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	changes, _, err := s.Changes(ctx, db.ChangesOptions{})
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	want := []string{models.EventCreate, models.EventUpdate, models.EventDelete}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d", len(want), len(changes))
+	}
+	for i, change := range changes {
+		if change.Type != want[i] || change.Revision != uint64(i+1) || change.Key != "a" {
+			t.Errorf("change %d: expected %s at revision %d, got %+v", i, want[i], i+1, change)
+		}
+	}
+	if changes[2].Value["n"] != 2.0 {
+		t.Errorf("expected delete to carry the removed value, got %v", changes[2].Value)
+	}
+
+	page, revision, err := s.Changes(ctx, db.ChangesOptions{Since: 1, Limit: 1})
+	if err != nil {
+		t.Fatalf("changes since 1: %v", err)
+	}
+	if len(page) != 1 || page[0].Revision != 2 || revision != 2 {
+		t.Errorf("expected revision 2, got %+v, next %d", page, revision)
+	}
+
+	// Прочитанный до конца журнал продолжается с его последней ревизии
+	if _, revision, err = s.Changes(ctx, db.ChangesOptions{Since: 3}); err != nil || revision != 3 {
+		t.Errorf("expected revision 3 at the end of the log, got %d, err %v", revision, err)
+	}
+}
+
+func TestMemoryStorage_ChangesCompaction(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 0.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	const writes = 15000
+	for n := 1; n < writes; n++ {
+		if _, err := s.Update(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": float64(n)}}, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	if _, _, err := s.Changes(ctx, db.ChangesOptions{}); !errors.Is(err, db.ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	changes, _, err := s.Changes(ctx, db.ChangesOptions{Since: writes - 10000})
+	if err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	if len(changes) == 0 || changes[0].Revision != writes-10000+1 {
+		t.Errorf("expected the last 10000 changes to be kept, got %d", len(changes))
+	}
+
+	// После компакции чтение продолжается с текущей ревизии
+	_, head, err := s.Changes(ctx, db.ChangesOptions{FromHead: true})
+	if err != nil || head != writes {
+		t.Errorf("expected head revision %d, got %d, err %v", writes, head, err)
+	}
+}
+
+func TestMemoryStorage_HistoryAndRestore(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithHistorySize(2))
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, n := range []float64{2, 3} {
+		if _, err := s.Update(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": n}}, 0); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+
+	history, err := s.History(ctx, "a")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 || history[0].Version != 3 || history[1].Version != 2 {
+		t.Fatalf("expected versions 3 and 2, got %+v", history)
+	}
+	if _, err := s.GetVersion(ctx, "a", 1); !errors.Is(err, db.ErrVersionNotFound) {
+		t.Errorf("expected trimmed version to be gone, got %v", err)
+	}
+
+	restored, err := db.Restore(ctx, s, "a", 2, 0)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Version != 4 || restored.Value["n"] != 2.0 {
+		t.Errorf("expected version 4 with version 2 value, got %+v", restored)
+	}
+
+	// Без корзины история удаляется вместе с ключом
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.History(ctx, "a"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected history of deleted key to be purged, got %v", err)
+	}
+}
+
+func TestMemoryStorage_Trash(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithTrashRetention(time.Hour))
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	trashed, _, err := s.ListTrash(ctx, db.ListOptions{})
+	if err != nil {
+		t.Fatalf("list trash: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].Key != "a" || trashed[0].DeletedAt.IsZero() {
+		t.Fatalf("expected deleted key in trash, got %+v", trashed)
+	}
+	if _, err := s.History(ctx, "a"); err != nil {
+		t.Errorf("expected history to be kept while key is in trash, got %v", err)
+	}
+
+	restored, err := s.RestoreTrash(ctx, "a")
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Version != 2 || restored.Value["n"] != 1.0 {
+		t.Errorf("expected key restored with next version, got %+v", restored)
+	}
+	if _, err := s.RestoreTrash(ctx, "a"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected trash to be empty after restore, got %v", err)
+	}
+	if history, err := s.History(ctx, "a"); err != nil || len(history) != 2 {
+		t.Errorf("expected history to survive restore, got %d versions, err %v", len(history), err)
+	}
+}
+
+func TestMemoryStorage_TrashPurgeRemovesHistory(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithTrashRetention(time.Millisecond))
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, _, err := s.ListTrash(ctx, db.ListOptions{}); err != nil {
+		t.Fatalf("list trash: %v", err)
+	}
+	if _, err := s.History(ctx, "a"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected history to be purged with trash, got %v", err)
+	}
+}
+
+func TestMemoryStorage_ExpireIntervalPurgesTrash(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithTrashRetention(20*time.Millisecond), db.WithExpireInterval(10*time.Millisecond))
+	defer s.Close()
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// Корзина очищается без обращений к ней: вместе с ключом удаляется история
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := s.History(ctx, "a"); errors.Is(err, db.ErrNotFound) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected trash to be purged in background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryStorage_BatchRollbackKeepsTrash(t *testing.T) {
+	logger.Init()
+	s := db.NewMemoryStorage(db.WithTrashRetention(20 * time.Millisecond))
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := s.Create(ctx, &models.KeyValue{Key: key, Value: map[string]interface{}{"n": 1.0}}); err != nil {
+			t.Fatalf("create %s: %v", key, err)
+		}
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	// Удаление в пакете наступает на срок очистки корзины, но пакет откатывается
+	results, err := s.Batch(ctx, []models.BatchOperation{
+		{Op: models.BatchDelete, Key: "b"},
+		{Op: models.BatchUpdate, Key: "missing", Value: map[string]interface{}{"n": 2.0}},
+	}, true)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if results[1].Err == nil {
+		t.Fatal("expected batch to fail")
+	}
+
+	// Очистка удалила бы историю ключа a вместе с ним
+	if _, err := s.History(ctx, "a"); err != nil {
+		t.Errorf("expected rolled back batch to keep trash, got %v", err)
+	}
+}
+
+func TestMemoryStorage_HardDeleteByDefault(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.RestoreTrash(ctx, "a"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected nothing in trash, got %v", err)
+	}
+}
+
+func TestMemoryStorage_MetadataAndLabels(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	created, err := s.Create(ctx, &models.KeyValue{
+		Key:    "app/a",
+		Value:  map[string]interface{}{"n": 1.0},
+		Labels: map[string]string{"team": "payments"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.CreatedAt == nil || created.UpdatedAt == nil {
+		t.Fatalf("expected timestamps, got %+v", created)
+	}
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "app/b", Value: map[string]interface{}{"n": 2.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Без меток в запросе обновление сохраняет прежние метки и время создания
+	updated, err := s.Update(ctx, &models.KeyValue{Key: "app/a", Value: map[string]interface{}{"n": 3.0}}, 0)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Labels["team"] != "payments" {
+		t.Errorf("expected labels to be kept, got %v", updated.Labels)
+	}
+	if !updated.CreatedAt.Equal(*created.CreatedAt) {
+		t.Errorf("expected created_at %v, got %v", created.CreatedAt, updated.CreatedAt)
+	}
+
+	selector, err := db.ParseLabelSelector("team=payments")
+	if err != nil {
+		t.Fatalf("parse selector: %v", err)
+	}
+	items, _, err := s.List(ctx, db.ListOptions{Prefix: "app/", Labels: selector})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != "app/a" {
+		t.Errorf("expected only app/a, got %v", items)
+	}
+
+	selector, _ = db.ParseLabelSelector("!team")
+	items, _, err = s.List(ctx, db.ListOptions{Prefix: "app/", Labels: selector})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 1 || items[0].Key != "app/b" {
+		t.Errorf("expected only app/b, got %v", items)
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	selector, err := db.ParseLabelSelector("team=payments, env!=prod,owner,!legacy")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(selector) != 4 {
+		t.Fatalf("expected 4 requirements, got %v", selector)
+	}
+
+	if !selector.Matches(map[string]string{"team": "payments", "env": "dev", "owner": "x"}) {
+		t.Error("expected labels to match")
+	}
+	if selector.Matches(map[string]string{"team": "payments", "env": "prod", "owner": "x"}) {
+		t.Error("expected env=prod not to match")
+	}
+	if selector.Matches(map[string]string{"team": "payments", "owner": "x", "legacy": "1"}) {
+		t.Error("expected legacy label not to match")
+	}
+
+	if _, err := db.ParseLabelSelector("a=b,,c"); !errors.Is(err, db.ErrInvalidSelector) {
+		t.Errorf("expected ErrInvalidSelector, got %v", err)
+	}
+}
+
+func TestMemoryNamespaces_Isolation(t *testing.T) {
+	logger.Init()
+	n := db.NewMemoryNamespaces()
+	ctx := context.Background()
+
+	if _, err := n.CreateNamespace(ctx, "team-a"); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+	if _, err := n.CreateNamespace(ctx, "team-a"); !errors.Is(err, db.ErrNamespaceExists) {
+		t.Errorf("expected ErrNamespaceExists, got %v", err)
+	}
+	if _, err := n.CreateNamespace(ctx, "Team A"); !errors.Is(err, db.ErrInvalidNamespace) {
+		t.Errorf("expected ErrInvalidNamespace, got %v", err)
+	}
+
+	teamA, err := n.Namespace(ctx, "team-a")
+	if err != nil {
+		t.Fatalf("open namespace: %v", err)
+	}
+	defaultStorage, err := n.Namespace(ctx, db.DefaultNamespace)
+	if err != nil {
+		t.Fatalf("open default namespace: %v", err)
+	}
+
+	if _, err := teamA.Create(ctx, &models.KeyValue{Key: "k", Value: map[string]interface{}{"ns": "team-a"}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := defaultStorage.Get(ctx, "k"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected key to be invisible in default namespace, got %v", err)
+	}
+
+	namespaces, err := n.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("list namespaces: %v", err)
+	}
+	if len(namespaces) != 2 || namespaces[0].Name != db.DefaultNamespace || namespaces[1].Name != "team-a" {
+		t.Errorf("unexpected namespaces %+v", namespaces)
+	}
+
+	if err := n.DeleteNamespace(ctx, db.DefaultNamespace); !errors.Is(err, db.ErrDefaultNamespace) {
+		t.Errorf("expected ErrDefaultNamespace, got %v", err)
+	}
+	if err := n.DeleteNamespace(ctx, "team-a"); err != nil {
+		t.Fatalf("delete namespace: %v", err)
+	}
+	if _, err := n.Namespace(ctx, "team-a"); !errors.Is(err, db.ErrNamespaceNotFound) {
+		t.Errorf("expected ErrNamespaceNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryTrashed - ключ в корзине MemoryStorage
+type memoryTrashed struct {
+	item      memoryItem
+	deletedAt time.Time
+}
+
+// purgeTrashLocked удаляет из корзины ключи с истекшим сроком хранения
+// вместе с их историей. История ключа, созданного заново, сохраняется.
+func (m *MemoryStorage) purgeTrashLocked(now time.Time) {
+	m.trashPurgedAt = now
+	for key, trashed := range m.trash {
+		if !trashed.deletedAt.Add(m.trashRetention).After(now) {
+			delete(m.trash, key)
+			if _, ok := m.items[key]; !ok {
+				delete(m.history, key)
+			}
+		}
+	}
+}
+
+// purgeTrashDueLocked очищает корзину, если с прошлой очистки прошел срок
+// хранения. Вызывается после удаления ключей, чтобы корзина не росла без
+// листинга, и только когда изменения уже не откатятся.
+func (m *MemoryStorage) purgeTrashDueLocked(now time.Time) {
+	if m.trashRetention > 0 && now.Sub(m.trashPurgedAt) >= m.trashRetention {
+		m.purgeTrashLocked(now)
+	}
+}
+
+// ListTrash возвращает страницу ключей корзины с заданным префиксом и метками
+func (m *MemoryStorage) ListTrash(ctx context.Context, opts ListOptions) ([]*models.TrashedKeyValue, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	after, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		log.LogInfo(ctx, "Invalid list cursor", logrus.Fields{"cursor": opts.Cursor})
+		return nil, "", err
+	}
+	limit := opts.normalizedLimit()
+
+	m.mu.Lock()
+	m.purgeTrashLocked(time.Now())
+	keys := make([]string, 0, len(m.trash))
+	for key := range m.trash {
+		if strings.HasPrefix(key, opts.Prefix) && key > after && opts.Labels.Matches(m.trash[key].item.labels) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	nextCursor := ""
+	if len(keys) > limit {
+		keys = keys[:limit]
+		nextCursor = encodeCursor(keys[limit-1])
+	}
+
+	snapshot := make([]memoryTrashed, len(keys))
+	for i, key := range keys {
+		snapshot[i] = m.trash[key]
+	}
+	m.mu.Unlock()
+
+	items := make([]*models.TrashedKeyValue, 0, len(keys))
+	for i, key := range keys {
+		trashed := snapshot[i].item
+		trashed.expiresAt = 0
+		item, err := decodeMemoryItem(key, trashed)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, &models.TrashedKeyValue{KeyValue: *item, DeletedAt: snapshot[i].deletedAt})
+	}
+	return items, nextCursor, nil
+}
+
+// RestoreTrash возвращает ключ из корзины со следующей версией и без TTL.
+// Время создания и метки ключа сохраняются.
+func (m *MemoryStorage) RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to restore key: %w", err)
+	}
+
+	m.mu.Lock()
+	m.purgeTrashLocked(time.Now())
+	trashed, ok := m.trash[key]
+	if !ok {
+		m.mu.Unlock()
+		log.LogInfo(ctx, "Key not found in trash", logrus.Fields{"key": key})
+		return nil, ErrNotFound
+	}
+	if current, ok := m.items[key]; current.alive(ok) {
+		m.mu.Unlock()
+		log.LogInfo(ctx, "Key already exists during restore", logrus.Fields{"key": key})
+		return nil, ErrAlreadyExists
+	}
+
+	item := trashed.item
+	item.version++
+	item.expiresAt = 0
+	item.updatedAt = time.Now().UTC()
+	m.items[key] = item
+	delete(m.trash, key)
+	m.recordLocked(models.EventCreate, key, item)
+	m.mu.Unlock()
+
+	log.LogInfo(ctx, "Key successfully restored from trash", logrus.Fields{"key": key})
+	return decodeMemoryItem(key, item)
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxModifyAttempts - число попыток Modify при конкурентных записях
+const maxModifyAttempts = 5
+
+// ErrConflict возвращается, если Modify не смог записать значение
+// из-за конкурентных изменений ключа
+var ErrConflict = errors.New("concurrent modification")
+
+// ModifyFunc вычисляет новое значение ключа по текущему.
+// Переданный документ можно изменять на месте.
+type ModifyFunc func(value map[string]interface{}) (map[string]interface{}, error)
+
+// Modify выполняет чтение-изменение-запись ключа под проверкой версии,
+// поэтому изменение не может затереть чужую запись. Если ifVersion не равен
+// нулю, изменение применяется только к этой версии ключа. Иначе при
+// конкурентной записи попытка повторяется со свежим значением.
+// Ключ без версии записывается, только если его еще никто не изменил.
+// TTL ключа сохраняется.
+func Modify(ctx context.Context, storage Storage, key string, ifVersion uint64, fn ModifyFunc) (*models.KeyValue, error) {
+	for attempt := 1; attempt <= maxModifyAttempts; attempt++ {
+		current, err := storage.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !versionMatches(current.Version, ifVersion) {
+			return nil, fmt.Errorf("failed to modify key: %w", ErrVersionMismatch)
+		}
+
+		value, err := fn(current.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := storage.Update(ctx, &models.KeyValue{
+			Key:       key,
+			Value:     value,
+			ExpiresAt: current.ExpiresAt,
+		}, ExpectVersion(current.Version))
+		if errors.Is(err, ErrVersionMismatch) && ifVersion == 0 {
+			log.LogInfo(ctx, "Concurrent modification, retrying", logrus.Fields{"key": key, "attempt": attempt})
+			continue
+		}
+		return updated, err
+	}
+
+	return nil, fmt.Errorf("failed to modify key after %d attempts: %w", maxModifyAttempts, ErrConflict)
+}
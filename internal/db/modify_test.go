@@ -0,0 +1,84 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestModify_ConcurrentUpdatesAreNotLost(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "counter", Value: map[string]interface{}{"n": float64(0)}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	const workers = 4
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.Modify(ctx, s, "counter", 0, func(value map[string]interface{}) (map[string]interface{}, error) {
+				value["n"] = value["n"].(float64) + 1
+				return value, nil
+			})
+			if err != nil {
+				t.Errorf("modify: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.Get(ctx, "counter")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Value["n"] != float64(workers) {
+		t.Errorf("expected counter %d, got %v", workers, got.Value["n"])
+	}
+}
+
+func TestModify_UnversionedKeyKeepsVersionCheck(t *testing.T) {
+	logger.Init()
+	ctrl := gomock.NewController(t)
+	storage := db.NewMockStorage(ctrl)
+	ctx := context.Background()
+
+	legacy := &models.KeyValue{Key: "legacy", Value: map[string]interface{}{"n": float64(0)}}
+	storage.EXPECT().Get(ctx, "legacy").Return(legacy, nil)
+	storage.EXPECT().Update(ctx, gomock.Any(), db.Unversioned).Return(nil, db.ErrVersionMismatch)
+	storage.EXPECT().Get(ctx, "legacy").Return(&models.KeyValue{Key: "legacy", Value: map[string]interface{}{"n": float64(1)}, Version: 1}, nil)
+	storage.EXPECT().Update(ctx, gomock.Any(), uint64(1)).Return(&models.KeyValue{Key: "legacy", Version: 2}, nil)
+
+	got, err := db.Modify(ctx, storage, "legacy", 0, func(value map[string]interface{}) (map[string]interface{}, error) {
+		return value, nil
+	})
+	if err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+	if got.Version != 2 {
+		t.Errorf("expected version 2, got %d", got.Version)
+	}
+}
+
+func TestMemoryStorage_UnversionedMismatch(t *testing.T) {
+	s := setupMemory(t)
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "k", Value: map[string]interface{}{"a": "b"}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	_, err := s.Update(ctx, &models.KeyValue{Key: "k", Value: map[string]interface{}{"a": "c"}}, db.Unversioned)
+	if !errors.Is(err, db.ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import "fmt"
+
+// decodeValue приводит значение, декодированное из msgpack, к модели
+// encoding/json: ключи map становятся строками, а числа - float64,
+// как и у значений, пришедших в API в формате JSON
+func decodeValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for key, child := range val {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected map key type %T", key)
+			}
+			decoded, err := decodeValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[name] = decoded
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for name, child := range val {
+			decoded, err := decodeValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[name] = decoded
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			decoded, err := decodeValue(child)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = decoded
+		}
+		return out, nil
+	case float32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case uint64:
+		return float64(val), nil
+	case nil, bool, string, float64:
+		return val, nil
+	default:
+		return nil, fmt.Errorf("unexpected value type %T", v)
+	}
+}
+
+// decodeObject декодирует поле value кортежа, которое должно быть map
+func decodeObject(v interface{}) (map[string]interface{}, error) {
+	decoded, err := decodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type %T", v)
+	}
+	return object, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// DefaultNamespace - пространство имен, в котором работают маршруты /kv.
+// Оно существует всегда и не может быть удалено.
+const DefaultNamespace = "default"
+
+var (
+	ErrNamespaceNotFound = errors.New("namespace not found")
+	ErrNamespaceExists   = errors.New("namespace already exists")
+	ErrInvalidNamespace  = errors.New("invalid namespace name")
+	// ErrDefaultNamespace возвращается при попытке удалить DefaultNamespace
+	ErrDefaultNamespace = errors.New("default namespace cannot be deleted")
+)
+
+// namespacePattern ограничивает имя символами, допустимыми в именах space Tarantool
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidateNamespace проверяет имя пространства имен: от 1 до 63 строчных
+// латинских букв, цифр, '_' и '-', начиная с буквы или цифры
+func ValidateNamespace(name string) error {
+	if !namespacePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidNamespace, name)
+	}
+	return nil
+}
+
+// Namespaces управляет пространствами имен. Ключи, история, корзина и
+// журнал изменений каждого пространства изолированы от остальных.
+//
+//go:generate mockgen -source=namespace.go -destination=namespace_mock.go -package=db Namespaces
+type Namespaces interface {
+	// Namespace возвращает хранилище ключей пространства имен. Реализация
+	// может не проверять существование пространства заранее: тогда
+	// ErrNamespaceNotFound вернут методы хранилища.
+	Namespace(ctx context.Context, name string) (Storage, error)
+	CreateNamespace(ctx context.Context, name string) (*models.Namespace, error)
+	// ListNamespaces возвращает все пространства имен в порядке возрастания имени
+	ListNamespaces(ctx context.Context) ([]*models.Namespace, error)
+	// DeleteNamespace удаляет пространство имен вместе со всеми его ключами
+	DeleteNamespace(ctx context.Context, name string) error
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: namespace.go
+//
+// Generated by this command:
+//
+//	mockgen -source=namespace.go -destination=namespace_mock.go -package=db Namespaces
+//
+
+// Package db is a generated GoMock package.
+package db
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/MosinFAM/tarantool-kv/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNamespaces is a mock of Namespaces interface.
+type MockNamespaces struct {
+	ctrl     *gomock.Controller
+	recorder *MockNamespacesMockRecorder
+	isgomock struct{}
+}
+
+// MockNamespacesMockRecorder is the mock recorder for MockNamespaces.
+type MockNamespacesMockRecorder struct {
+	mock *MockNamespaces
+}
+
+// NewMockNamespaces creates a new mock instance.
+func NewMockNamespaces(ctrl *gomock.Controller) *MockNamespaces {
+	mock := &MockNamespaces{ctrl: ctrl}
+	mock.recorder = &MockNamespacesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNamespaces) EXPECT() *MockNamespacesMockRecorder {
+	return m.recorder
+}
+
+// CreateNamespace mocks base method.
+func (m *MockNamespaces) CreateNamespace(ctx context.Context, name string) (*models.Namespace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNamespace", ctx, name)
+	ret0, _ := ret[0].(*models.Namespace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNamespace indicates an expected call of CreateNamespace.
+func (mr *MockNamespacesMockRecorder) CreateNamespace(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNamespace", reflect.TypeOf((*MockNamespaces)(nil).CreateNamespace), ctx, name)
+}
+
+// DeleteNamespace mocks base method.
+func (m *MockNamespaces) DeleteNamespace(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNamespace", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteNamespace indicates an expected call of DeleteNamespace.
+func (mr *MockNamespacesMockRecorder) DeleteNamespace(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNamespace", reflect.TypeOf((*MockNamespaces)(nil).DeleteNamespace), ctx, name)
+}
+
+// ListNamespaces mocks base method.
+func (m *MockNamespaces) ListNamespaces(ctx context.Context) ([]*models.Namespace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNamespaces", ctx)
+	ret0, _ := ret[0].([]*models.Namespace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNamespaces indicates an expected call of ListNamespaces.
+func (mr *MockNamespacesMockRecorder) ListNamespaces(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNamespaces", reflect.TypeOf((*MockNamespaces)(nil).ListNamespaces), ctx)
+}
+
+// Namespace mocks base method.
+func (m *MockNamespaces) Namespace(ctx context.Context, name string) (Storage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Namespace", ctx, name)
+	ret0, _ := ret[0].(Storage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Namespace indicates an expected call of Namespace.
+func (mr *MockNamespacesMockRecorder) Namespace(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Namespace", reflect.TypeOf((*MockNamespaces)(nil).Namespace), ctx, name)
+}
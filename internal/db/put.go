@@ -0,0 +1,24 @@
+package db
+
+// PutMode задает поведение Put в зависимости от существования ключа
+type PutMode string
+
+const (
+	// PutUpsert создает ключ или заменяет значение существующего
+	PutUpsert PutMode = "upsert"
+	// PutCreate создает ключ, для существующего возвращается ErrAlreadyExists
+	PutCreate PutMode = "create"
+	// PutReplace заменяет значение существующего ключа, для отсутствующего
+	// возвращается ErrNotFound
+	PutReplace PutMode = "replace"
+)
+
+// Valid сообщает, известен ли режим записи
+func (m PutMode) Valid() bool {
+	switch m {
+	case PutUpsert, PutCreate, PutReplace:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,13 +1,48 @@
 package db
 
-import "github.com/MosinFAM/tarantool-kv/internal/models"
+import (
+	"context"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
 
 // go install go.uber.org/mock/mockgen@latest
 //
+// Все методы учитывают отмену и дедлайн переданного контекста.
+//
 //go:generate mockgen -source=storage.go -destination=storage_mock.go -package=db StorageRepo
 type Storage interface {
-	Create(in *models.KeyValue) (*models.KeyValue, error)
-	Get(key string) (*models.KeyValue, error)
-	Update(in *models.KeyValue) (*models.KeyValue, error)
-	Delete(key string) (*models.KeyValue, error)
+	Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error)
+	Get(ctx context.Context, key string) (*models.KeyValue, error)
+	// Update и Delete с ненулевым ifVersion выполняются, только если текущая
+	// версия ключа равна ifVersion, иначе возвращается ErrVersionMismatch.
+	// Ключу без версии соответствует ifVersion Unversioned.
+	// При включенном мягком удалении Delete перемещает ключ в корзину.
+	Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error)
+	Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error)
+	// Put записывает значение в режиме mode и сообщает, был ли ключ создан.
+	// Ненулевой ifVersion требует, чтобы ключ существовал с этой версией.
+	Put(ctx context.Context, in *models.KeyValue, mode PutMode, ifVersion uint64) (*models.KeyValue, bool, error)
+	// List возвращает страницу ключей и курсор следующей страницы,
+	// пустой курсор означает, что страниц больше нет
+	List(ctx context.Context, opts ListOptions) ([]*models.KeyValue, string, error)
+	// Batch выполняет операции по порядку и возвращает результат каждой из них.
+	// Ошибка возвращается, только если пакет не удалось выполнить целиком.
+	Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchResult, error)
+	// History возвращает сохраненные версии ключа от новой к старой,
+	// включая текущую. История удаленного ключа удаляется вместе с ним,
+	// а при мягком удалении - вместе с его удалением из корзины.
+	History(ctx context.Context, key string) ([]*models.KeyValue, error)
+	// GetVersion возвращает версию ключа из истории или ErrVersionNotFound
+	GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error)
+	// ListTrash возвращает страницу мягко удаленных ключей. Если мягкое
+	// удаление выключено, корзина пуста.
+	ListTrash(ctx context.Context, opts ListOptions) ([]*models.TrashedKeyValue, string, error)
+	// RestoreTrash возвращает ключ из корзины. Если ключ с тем же именем
+	// уже существует, возвращается ErrAlreadyExists.
+	RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error)
+	// Changes возвращает записи журнала изменений с ревизией больше opts.Since
+	// и ревизию, которую нужно передать в Since следующего чтения.
+	// Если часть этих записей удалена компакцией, возвращается ErrRevisionCompacted.
+	Changes(ctx context.Context, opts ChangesOptions) ([]*models.Change, uint64, error)
 }
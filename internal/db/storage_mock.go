@@ -10,6 +10,7 @@
 package db
 
 import (
+	context "context"
 	reflect "reflect"
 
 	models "github.com/MosinFAM/tarantool-kv/internal/models"
@@ -40,62 +41,186 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// Batch mocks base method.
+func (m *MockStorage) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Batch", ctx, ops, atomic)
+	ret0, _ := ret[0].([]BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Batch indicates an expected call of Batch.
+func (mr *MockStorageMockRecorder) Batch(ctx, ops, atomic any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Batch", reflect.TypeOf((*MockStorage)(nil).Batch), ctx, ops, atomic)
+}
+
+// Changes mocks base method.
+func (m *MockStorage) Changes(ctx context.Context, opts ChangesOptions) ([]*models.Change, uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", ctx, opts)
+	ret0, _ := ret[0].([]*models.Change)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockStorageMockRecorder) Changes(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockStorage)(nil).Changes), ctx, opts)
+}
+
 // Create mocks base method.
-func (m *MockStorage) Create(in *models.KeyValue) (*models.KeyValue, error) {
+func (m *MockStorage) Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", in)
+	ret := m.ctrl.Call(m, "Create", ctx, in)
 	ret0, _ := ret[0].(*models.KeyValue)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorageMockRecorder) Create(in any) *gomock.Call {
+func (mr *MockStorageMockRecorder) Create(ctx, in any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorage)(nil).Create), in)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorage)(nil).Create), ctx, in)
 }
 
 // Delete mocks base method.
-func (m *MockStorage) Delete(key string) (*models.KeyValue, error) {
+func (m *MockStorage) Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", key)
+	ret := m.ctrl.Call(m, "Delete", ctx, key, ifVersion)
 	ret0, _ := ret[0].(*models.KeyValue)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockStorageMockRecorder) Delete(key any) *gomock.Call {
+func (mr *MockStorageMockRecorder) Delete(ctx, key, ifVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorage)(nil).Delete), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorage)(nil).Delete), ctx, key, ifVersion)
 }
 
 // Get mocks base method.
-func (m *MockStorage) Get(key string) (*models.KeyValue, error) {
+func (m *MockStorage) Get(ctx context.Context, key string) (*models.KeyValue, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", key)
+	ret := m.ctrl.Call(m, "Get", ctx, key)
 	ret0, _ := ret[0].(*models.KeyValue)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockStorageMockRecorder) Get(key any) *gomock.Call {
+func (mr *MockStorageMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), ctx, key)
+}
+
+// GetVersion mocks base method.
+func (m *MockStorage) GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersion", ctx, key, version)
+	ret0, _ := ret[0].(*models.KeyValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVersion indicates an expected call of GetVersion.
+func (mr *MockStorageMockRecorder) GetVersion(ctx, key, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockStorage)(nil).GetVersion), ctx, key, version)
+}
+
+// History mocks base method.
+func (m *MockStorage) History(ctx context.Context, key string) ([]*models.KeyValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, key)
+	ret0, _ := ret[0].([]*models.KeyValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockStorageMockRecorder) History(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockStorage)(nil).History), ctx, key)
+}
+
+// List mocks base method.
+func (m *MockStorage) List(ctx context.Context, opts ListOptions) ([]*models.KeyValue, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, opts)
+	ret0, _ := ret[0].([]*models.KeyValue)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List.
+func (mr *MockStorageMockRecorder) List(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockStorage)(nil).List), ctx, opts)
+}
+
+// ListTrash mocks base method.
+func (m *MockStorage) ListTrash(ctx context.Context, opts ListOptions) ([]*models.TrashedKeyValue, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrash", ctx, opts)
+	ret0, _ := ret[0].([]*models.TrashedKeyValue)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListTrash indicates an expected call of ListTrash.
+func (mr *MockStorageMockRecorder) ListTrash(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrash", reflect.TypeOf((*MockStorage)(nil).ListTrash), ctx, opts)
+}
+
+// Put mocks base method.
+func (m *MockStorage) Put(ctx context.Context, in *models.KeyValue, mode PutMode, ifVersion uint64) (*models.KeyValue, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, in, mode, ifVersion)
+	ret0, _ := ret[0].(*models.KeyValue)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockStorageMockRecorder) Put(ctx, in, mode, ifVersion any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockStorage)(nil).Put), ctx, in, mode, ifVersion)
+}
+
+// RestoreTrash mocks base method.
+func (m *MockStorage) RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreTrash", ctx, key)
+	ret0, _ := ret[0].(*models.KeyValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreTrash indicates an expected call of RestoreTrash.
+func (mr *MockStorageMockRecorder) RestoreTrash(ctx, key any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreTrash", reflect.TypeOf((*MockStorage)(nil).RestoreTrash), ctx, key)
 }
 
 // Update mocks base method.
-func (m *MockStorage) Update(in *models.KeyValue) (*models.KeyValue, error) {
+func (m *MockStorage) Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", in)
+	ret := m.ctrl.Call(m, "Update", ctx, in, ifVersion)
 	ret0, _ := ret[0].(*models.KeyValue)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockStorageMockRecorder) Update(in any) *gomock.Call {
+func (mr *MockStorageMockRecorder) Update(ctx, in, ifVersion any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorage)(nil).Update), in)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorage)(nil).Update), ctx, in, ifVersion)
 }
@@ -1,24 +1,41 @@
 package db
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"regexp"
+	"time"
 
-	"github.com/MosinFAM/tarantool-kv/internal/logger"
 	"github.com/MosinFAM/tarantool-kv/internal/models"
 
 	"github.com/sirupsen/logrus"
 	tarantool "github.com/tarantool/go-tarantool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer создает спаны вызовов Tarantool. Пока провайдер не установлен,
+// спаны не записываются.
+var tracer = otel.Tracer("github.com/MosinFAM/tarantool-kv/internal/db")
+
+// KeyValueManager работает с ключами одного пространства имен Tarantool
+// и управляет пространствами имен
 type KeyValueManager struct {
-	tConn *tarantool.Connection
+	tConn     *tarantool.Connection
+	namespace string
 }
 
+var (
+	_ Storage    = (*KeyValueManager)(nil)
+	_ Namespaces = (*KeyValueManager)(nil)
+)
+
+// NewKeyValueManager возвращает хранилище пространства имен DefaultNamespace
 func NewKeyValueManager(conn *tarantool.Connection) *KeyValueManager {
-	return &KeyValueManager{tConn: conn}
+	return &KeyValueManager{tConn: conn, namespace: DefaultNamespace}
 }
 
 func ConnectTarantool() (*tarantool.Connection, error) {
@@ -37,114 +54,871 @@ func ConnectTarantool() (*tarantool.Connection, error) {
 
 	conn, err := tarantool.Connect(addr, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Tarantool: %w", err)
+		return nil, fmt.Errorf("failed to connect to Tarantool: %w: %w", ErrBackendUnavailable, err)
 	}
 
-	logger.LogInfo("Connected to Tarantool at", logrus.Fields{"addr": addr})
+	log.LogInfo(context.Background(), "Connected to Tarantool at", logrus.Fields{"addr": addr})
 	return conn, nil
 }
 
 // Create добавляет новую пару ключ-значение в Tarantool
-func (kv *KeyValueManager) Create(in *models.KeyValue) (*models.KeyValue, error) {
-	logger.LogInfo("Start creating key-value", logrus.Fields{"key-value": in})
-	dataSerialized, err := json.Marshal(in.Value)
+func (kv *KeyValueManager) Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start creating key-value", logrus.Fields{"key-value": in})
+	resp, err := kv.call(ctx, "insert_kv", []interface{}{kv.namespace, in.Key, in.Value, expiresAtUnix(in), in.Labels})
 	if err != nil {
-		logger.LogError("Data serialization failed", err, logrus.Fields{"key": in.Key})
-		return nil, fmt.Errorf("data serialization failed: %w", err)
+		err = wrapTarantoolError("failed to insert key", err)
+		if errors.Is(err, ErrAlreadyExists) {
+			log.LogInfo(ctx, "Key already exists during insert", logrus.Fields{"key": in.Key})
+			return nil, err
+		}
+
+		log.LogError(ctx, "Failed to insert key", err, logrus.Fields{"key": in.Key})
+		return nil, err
 	}
 
-	_, err = kv.tConn.Call("insert_kv", []interface{}{in.Key, string(dataSerialized)})
+	created, err := decodeTuple(firstTuple(resp))
 	if err != nil {
-		re := regexp.MustCompile(`key already exists`)
-		if re.MatchString(err.Error()) {
-			logger.LogError("Key already exists during insert", err, logrus.Fields{"key": in.Key})
-			return nil, fmt.Errorf("key already exists")
+		log.LogError(ctx, "Failed to decode created tuple", err, logrus.Fields{"key": in.Key})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully created", logrus.Fields{"key": in.Key, "version": created.Version})
+	return created, nil
+}
+
+// Get получает значение по ключу
+func (kv *KeyValueManager) Get(ctx context.Context, key string) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start getting key", logrus.Fields{"key": key})
+	resp, err := kv.call(ctx, "get_kv", []interface{}{kv.namespace, key})
+	if err != nil {
+		log.LogError(ctx, "Failed to get key", err, logrus.Fields{"key": key})
+		return nil, wrapTarantoolError("failed to get key", err)
+	}
+
+	firstItem := firstTuple(resp)
+	if firstItem == nil {
+		log.LogInfo(ctx, "Key not found", logrus.Fields{"key": key})
+		return nil, ErrNotFound
+	}
+
+	item, err := decodeTuple(firstItem)
+	if err != nil {
+		log.LogError(ctx, "Failed to unmarshal value", err, logrus.Fields{"key": key})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully getted", logrus.Fields{"key": key, "Value": item.Value})
+	return item, nil
+}
+
+// List возвращает страницу ключей с заданным префиксом в порядке возрастания
+func (kv *KeyValueManager) List(ctx context.Context, opts ListOptions) ([]*models.KeyValue, string, error) {
+	log.LogDebug(ctx, "Start listing keys", logrus.Fields{"prefix": opts.Prefix, "cursor": opts.Cursor})
+	after, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		log.LogInfo(ctx, "Invalid list cursor", logrus.Fields{"cursor": opts.Cursor})
+		return nil, "", err
+	}
+
+	// Запрашиваем на один элемент больше, чтобы узнать, есть ли следующая страница
+	limit := opts.normalizedLimit()
+	resp, err := kv.call17(ctx, "list_kv", []interface{}{
+		kv.namespace, opts.Prefix, after, limit + 1, opts.Labels.args(),
+	})
+	if err != nil {
+		log.LogError(ctx, "Failed to list keys", err, logrus.Fields{"prefix": opts.Prefix})
+		return nil, "", wrapTarantoolError("failed to list keys", err)
+	}
+
+	var tuples []interface{}
+	if len(resp.Data) > 0 {
+		tuples, _ = resp.Data[0].([]interface{})
+	}
+
+	items := make([]*models.KeyValue, 0, len(tuples))
+	for _, raw := range tuples {
+		tuple, ok := raw.([]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("failed to list keys: unexpected tuple %v", raw)
+		}
+		item, err := decodeTuple(tuple)
+		if err != nil {
+			log.LogError(ctx, "Failed to unmarshal value", err, logrus.Fields{"prefix": opts.Prefix})
+			return nil, "", err
 		}
+		items = append(items, item)
+	}
 
-		logger.LogError("Failed to insert key", err, logrus.Fields{"key": in.Key})
-		return nil, fmt.Errorf("failed to insert key: %w", err)
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		nextCursor = encodeCursor(items[limit-1].Key)
 	}
 
-	logger.LogInfo("Key successfully created", logrus.Fields{"key": in.Key})
-	return in, nil
+	log.LogInfo(ctx, "Keys successfully listed", logrus.Fields{"prefix": opts.Prefix, "count": len(items)})
+	return items, nextCursor, nil
 }
 
-// Get получает значение по ключу
-func (kv *KeyValueManager) Get(key string) (*models.KeyValue, error) {
-	logger.LogInfo("Start getting key", logrus.Fields{"key": key})
-	resp, err := kv.tConn.Call("get_kv", []interface{}{key})
+// Delete удаляет ключ и возвращает удаленный кортеж. Если ifVersion не равен
+// нулю, ключ удаляется, только если его текущая версия совпадает с ifVersion.
+// Проверка и удаление выполняются одним вызовом delete_kv, поэтому
+// возвращается ровно то значение, которое было удалено.
+func (kv *KeyValueManager) Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start deleting key", logrus.Fields{"key": key})
+	resp, err := kv.call(ctx, "delete_kv", []interface{}{kv.namespace, key, ifVersion})
 	if err != nil {
-		logger.LogError("Failed to get key", err, logrus.Fields{"key": key})
-		return nil, fmt.Errorf("failed to get key: %w", err)
+		log.LogError(ctx, "Failed to delete key", err, logrus.Fields{"key": key})
+		return nil, wrapTarantoolError("failed to delete key", err)
 	}
 
-	if len(resp.Data) == 0 {
-		logger.LogInfo("Key not found", logrus.Fields{"key": key})
-		return nil, fmt.Errorf("key not found")
+	data := firstTuple(resp)
+	if data == nil {
+		log.LogInfo(ctx, "Key not found during delete", logrus.Fields{"key": key})
+		return nil, ErrNotFound
 	}
 
-	firstItem := resp.Data[0].([]interface{})
-	if len(firstItem) == 0 || firstItem[0] == nil {
-		logger.LogInfo("Value is nil or missing", logrus.Fields{"key": key})
-		return nil, fmt.Errorf("key not found")
+	deleted, err := decodeTuple(data)
+	if err != nil {
+		log.LogError(ctx, "Failed to decode deleted tuple", err, logrus.Fields{"key": key})
+		return nil, err
 	}
 
-	rawValue := firstItem[1].(string)
-	var value map[string]interface{}
-	if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
-		logger.LogError("Failed to unmarshal value", err, logrus.Fields{"key": key})
-		return nil, fmt.Errorf("failed to deserialize value: %w", err)
+	log.LogInfo(ctx, "Key successfully deleted", logrus.Fields{"key": key, "version": deleted.Version})
+	return deleted, nil
+}
+
+// Update обновляет значение для ключа и увеличивает его версию.
+// Время истечения заменяется значением из in, без него TTL снимается.
+// Если ifVersion не равен нулю, проверка версии и запись выполняются
+// атомарно в update_kv, при несовпадении возвращается ErrVersionMismatch.
+func (kv *KeyValueManager) Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start updating key-value", logrus.Fields{"key-value": in})
+	resp, err := kv.call(ctx, "update_kv", []interface{}{
+		kv.namespace, in.Key, in.Value, ifVersion, expiresAtUnix(in), in.Labels,
+	})
+	if err != nil {
+		log.LogError(ctx, "Failed to update key", err, logrus.Fields{"key": in.Key})
+		return nil, wrapTarantoolError("failed to update key", err)
+	}
+
+	data := firstTuple(resp)
+	if data == nil {
+		log.LogInfo(ctx, "Key not found during update", logrus.Fields{"key": in.Key})
+		return nil, ErrNotFound
+	}
+
+	updated, err := decodeTuple(data)
+	if err != nil {
+		log.LogError(ctx, "Failed to decode updated tuple", err, logrus.Fields{"key": in.Key})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully updated", logrus.Fields{"key": in.Key, "version": updated.Version})
+	return updated, nil
+}
+
+// Put записывает значение в режиме mode одним вызовом put_kv,
+// который атомарно проверяет существование и версию ключа
+func (kv *KeyValueManager) Put(ctx context.Context, in *models.KeyValue, mode PutMode, ifVersion uint64) (*models.KeyValue, bool, error) {
+	log.LogDebug(ctx, "Start putting key-value", logrus.Fields{"key-value": in, "mode": mode})
+	if !mode.Valid() {
+		return nil, false, fmt.Errorf("unknown put mode %q", mode)
+	}
+
+	resp, err := kv.call17(ctx, "put_kv", []interface{}{
+		kv.namespace, in.Key, in.Value, string(mode), ifVersion, expiresAtUnix(in), in.Labels,
+	})
+	if err != nil {
+		log.LogError(ctx, "Failed to put key", err, logrus.Fields{"key": in.Key})
+		return nil, false, wrapTarantoolError("failed to put key", err)
+	}
+
+	// put_kv возвращает кортеж и признак создания ключа
+	var tuple []interface{}
+	created := false
+	if len(resp.Data) > 0 {
+		tuple, _ = resp.Data[0].([]interface{})
+	}
+	if len(resp.Data) > 1 {
+		created, _ = resp.Data[1].(bool)
+	}
+	if tuple == nil {
+		log.LogInfo(ctx, "Key not found during put", logrus.Fields{"key": in.Key})
+		return nil, false, ErrNotFound
+	}
+
+	item, err := decodeTuple(tuple)
+	if err != nil {
+		log.LogError(ctx, "Failed to decode put tuple", err, logrus.Fields{"key": in.Key})
+		return nil, false, err
+	}
+
+	log.LogInfo(ctx, "Key successfully put", logrus.Fields{"key": in.Key, "version": item.Version, "created": created})
+	return item, created, nil
+}
+
+// Batch выполняет операции пакета одним вызовом batch_kv.
+// Атомарный пакет выполняется в транзакции Tarantool.
+func (kv *KeyValueManager) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]BatchResult, error) {
+	log.LogDebug(ctx, "Start executing batch", logrus.Fields{"operations": len(ops), "atomic": atomic})
+	args := make([]interface{}, 0, len(ops))
+	for i, op := range ops {
+		if err := validateBatchOp(op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		args = append(args, map[string]interface{}{
+			"op":         op.Op,
+			"key":        op.Key,
+			"value":      op.Value,
+			"if_version": op.IfVersion,
+			"expires_at": expiresAtUnix(&models.KeyValue{ExpiresAt: op.ExpiresAt}),
+			"labels":     op.Labels,
+		})
+	}
+
+	resp, err := kv.call17(ctx, "batch_kv", []interface{}{kv.namespace, args, atomic})
+	if err != nil {
+		log.LogError(ctx, "Failed to execute batch", err, logrus.Fields{"operations": len(ops)})
+		return nil, wrapTarantoolError("failed to execute batch", err)
+	}
+
+	var rows []interface{}
+	if len(resp.Data) > 0 {
+		rows, _ = resp.Data[0].([]interface{})
+	}
+
+	// batch_kv останавливается на первой ошибке атомарного пакета,
+	// поэтому результатов может быть меньше, чем операций
+	results := make([]BatchResult, len(ops))
+	failed := false
+	for i := range results {
+		if i >= len(rows) {
+			results[i] = BatchResult{Err: ErrBatchAborted}
+			continue
+		}
+		results[i] = decodeBatchRow(rows[i])
+		failed = failed || BatchFailed(ops[i], results[i].Err)
+	}
+	if atomic && failed {
+		abortBatch(results)
+	}
+
+	log.LogInfo(ctx, "Batch successfully executed", logrus.Fields{"operations": len(ops), "atomic": atomic})
+	return results, nil
+}
+
+// decodeBatchRow преобразует результат операции {tuple, error_code} из batch_kv
+func decodeBatchRow(raw interface{}) BatchResult {
+	row, ok := raw.([]interface{})
+	if !ok || len(row) == 0 {
+		return BatchResult{Err: fmt.Errorf("unexpected batch result %v", raw)}
+	}
+
+	if len(row) > 1 && row[1] != nil {
+		code, _ := row[1].(string)
+		return BatchResult{Err: batchError(code)}
+	}
+
+	tuple, ok := row[0].([]interface{})
+	if !ok {
+		return BatchResult{Err: fmt.Errorf("unexpected batch tuple %v", row[0])}
+	}
+	item, err := decodeTuple(tuple)
+	return BatchResult{Item: item, Err: err}
+}
+
+// batchError возвращает ошибку хранилища по коду ошибки операции из batch_kv
+func batchError(code string) error {
+	switch code {
+	case "not_found":
+		return ErrNotFound
+	case "already_exists":
+		return ErrAlreadyExists
+	case "version_mismatch":
+		return ErrVersionMismatch
+	default:
+		return fmt.Errorf("batch operation failed: %s", code)
+	}
+}
+
+// History возвращает сохраненные версии ключа из space kv_history
+func (kv *KeyValueManager) History(ctx context.Context, key string) ([]*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start getting history", logrus.Fields{"key": key})
+	resp, err := kv.call17(ctx, "history_kv", []interface{}{kv.namespace, key})
+	if err != nil {
+		log.LogError(ctx, "Failed to get history", err, logrus.Fields{"key": key})
+		return nil, wrapTarantoolError("failed to get history", err)
+	}
+
+	var tuples []interface{}
+	if len(resp.Data) > 0 {
+		tuples, _ = resp.Data[0].([]interface{})
+	}
+	if len(tuples) == 0 {
+		log.LogInfo(ctx, "History not found", logrus.Fields{"key": key})
+		return nil, ErrNotFound
+	}
+
+	items := make([]*models.KeyValue, 0, len(tuples))
+	for _, raw := range tuples {
+		tuple, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("failed to get history: unexpected tuple %v", raw)
+		}
+		item, err := decodeHistoryTuple(tuple)
+		if err != nil {
+			log.LogError(ctx, "Failed to decode history tuple", err, logrus.Fields{"key": key})
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetVersion возвращает версию ключа из space kv_history
+func (kv *KeyValueManager) GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start getting key version", logrus.Fields{"key": key, "version": version})
+	resp, err := kv.call17(ctx, "get_version_kv", []interface{}{kv.namespace, key, version})
+	if err != nil {
+		log.LogError(ctx, "Failed to get key version", err, logrus.Fields{"key": key})
+		return nil, wrapTarantoolError("failed to get version", err)
+	}
+
+	var tuple []interface{}
+	if len(resp.Data) > 0 {
+		tuple, _ = resp.Data[0].([]interface{})
+	}
+	if tuple == nil {
+		log.LogInfo(ctx, "Key version not found", logrus.Fields{"key": key, "version": version})
+		return nil, fmt.Errorf("failed to get version %d of key %q: %w", version, key, ErrVersionNotFound)
+	}
+
+	return decodeHistoryTuple(tuple)
+}
+
+// ListTrash возвращает страницу ключей из space kv_trash
+func (kv *KeyValueManager) ListTrash(ctx context.Context, opts ListOptions) ([]*models.TrashedKeyValue, string, error) {
+	log.LogDebug(ctx, "Start listing trash", logrus.Fields{"prefix": opts.Prefix, "cursor": opts.Cursor})
+	after, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		log.LogInfo(ctx, "Invalid list cursor", logrus.Fields{"cursor": opts.Cursor})
+		return nil, "", err
+	}
+
+	limit := opts.normalizedLimit()
+	resp, err := kv.call17(ctx, "list_trash_kv", []interface{}{
+		kv.namespace, opts.Prefix, after, limit + 1, opts.Labels.args(),
+	})
+	if err != nil {
+		log.LogError(ctx, "Failed to list trash", err, logrus.Fields{"prefix": opts.Prefix})
+		return nil, "", wrapTarantoolError("failed to list trash", err)
+	}
+
+	var tuples []interface{}
+	if len(resp.Data) > 0 {
+		tuples, _ = resp.Data[0].([]interface{})
+	}
+
+	items := make([]*models.TrashedKeyValue, 0, len(tuples))
+	for _, raw := range tuples {
+		tuple, ok := raw.([]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("failed to list trash: unexpected tuple %v", raw)
+		}
+		item, err := decodeTrashTuple(tuple)
+		if err != nil {
+			log.LogError(ctx, "Failed to decode trash tuple", err, logrus.Fields{"prefix": opts.Prefix})
+			return nil, "", err
+		}
+		items = append(items, item)
+	}
+
+	nextCursor := ""
+	if len(items) > limit {
+		items = items[:limit]
+		nextCursor = encodeCursor(items[limit-1].Key)
+	}
+	return items, nextCursor, nil
+}
+
+// RestoreTrash возвращает ключ из space kv_trash одним вызовом restore_trash_kv
+func (kv *KeyValueManager) RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error) {
+	log.LogDebug(ctx, "Start restoring key from trash", logrus.Fields{"key": key})
+	resp, err := kv.call(ctx, "restore_trash_kv", []interface{}{kv.namespace, key})
+	if err != nil {
+		err = wrapTarantoolError("failed to restore key", err)
+		log.LogError(ctx, "Failed to restore key from trash", err, logrus.Fields{"key": key})
+		return nil, err
+	}
+
+	data := firstTuple(resp)
+	if data == nil {
+		log.LogInfo(ctx, "Key not found in trash", logrus.Fields{"key": key})
+		return nil, ErrNotFound
+	}
+
+	restored, err := decodeTuple(data)
+	if err != nil {
+		log.LogError(ctx, "Failed to decode restored tuple", err, logrus.Fields{"key": key})
+		return nil, err
+	}
+
+	log.LogInfo(ctx, "Key successfully restored from trash", logrus.Fields{"key": key, "version": restored.Version})
+	return restored, nil
+}
+
+// Changes читает журнал изменений kv_changelog после ревизии opts.Since
+func (kv *KeyValueManager) Changes(ctx context.Context, opts ChangesOptions) ([]*models.Change, uint64, error) {
+	log.LogDebug(ctx, "Start reading changes", logrus.Fields{"since": opts.Since})
+	resp, err := kv.call17(ctx, "changes_kv", []interface{}{kv.namespace, opts.Since, opts.normalizedLimit(), opts.FromHead})
+	if err != nil {
+		log.LogError(ctx, "Failed to read changes", err, logrus.Fields{"since": opts.Since})
+		return nil, 0, wrapTarantoolError("failed to read changes", err)
 	}
 
-	logger.LogInfo("Key successfully getted", logrus.Fields{"key": key, "Value": value})
-	return &models.KeyValue{Key: key, Value: value}, nil
+	// changes_kv возвращает записи, ревизию последней удаленной компакцией
+	// записи и ревизию, с которой продолжать чтение
+	var entries []interface{}
+	var compacted, revision uint64
+	if len(resp.Data) > 0 {
+		entries, _ = resp.Data[0].([]interface{})
+	}
+	if len(resp.Data) > 1 && resp.Data[1] != nil {
+		compacted, _ = toUint64(resp.Data[1])
+	}
+	if len(resp.Data) > 2 && resp.Data[2] != nil {
+		revision, _ = toUint64(resp.Data[2])
+	}
+	if opts.FromHead {
+		return nil, revision, nil
+	}
+	if opts.Since < compacted {
+		log.LogInfo(ctx, "Changes since compacted revision requested", logrus.Fields{"since": opts.Since})
+		return nil, 0, fmt.Errorf("failed to read changes since %d: %w", opts.Since, ErrRevisionCompacted)
+	}
+
+	changes := make([]*models.Change, 0, len(entries))
+	for _, raw := range entries {
+		entry, ok := raw.([]interface{})
+		if !ok {
+			return nil, 0, fmt.Errorf("failed to read changes: unexpected entry %v", raw)
+		}
+		change, err := decodeChange(entry)
+		if err != nil {
+			log.LogError(ctx, "Failed to decode change", err, logrus.Fields{"since": opts.Since})
+			return nil, 0, err
+		}
+		changes = append(changes, change)
+	}
+
+	log.LogInfo(ctx, "Changes successfully read", logrus.Fields{"since": opts.Since, "count": len(changes)})
+	return changes, max(revision, opts.Since), nil
 }
 
-// Delete удаляет ключ
-func (kv *KeyValueManager) Delete(key string) (*models.KeyValue, error) {
-	logger.LogInfo("Start deleting key", logrus.Fields{"key": key})
-	existing, err := kv.Get(key)
+// Namespace возвращает хранилище пространства имен name на том же соединении.
+// Пространство ищется в реестре kv_namespaces, поэтому для несуществующего
+// имени возвращается ErrNamespaceNotFound, а не хранилище, каждый вызов
+// которого завершится ошибкой.
+func (kv *KeyValueManager) Namespace(ctx context.Context, name string) (Storage, error) {
+	if err := ValidateNamespace(name); err != nil {
+		return nil, fmt.Errorf("failed to open namespace: %w", ErrNamespaceNotFound)
+	}
+
+	resp, err := kv.call17(ctx, "get_namespace_kv", []interface{}{name})
 	if err != nil {
-		logger.LogInfo("Key not found during delete", logrus.Fields{"key": key})
-		return nil, fmt.Errorf("key not found")
+		log.LogError(ctx, "Failed to open namespace", err, logrus.Fields{"namespace": name})
+		return nil, wrapTarantoolError("failed to open namespace", err)
+	}
+	// get_namespace_kv возвращает nil, если пространства нет
+	if len(resp.Data) == 0 || resp.Data[0] == nil {
+		return nil, fmt.Errorf("failed to open namespace %q: %w", name, ErrNamespaceNotFound)
+	}
+	return &KeyValueManager{tConn: kv.tConn, namespace: name}, nil
+}
+
+// CreateNamespace создает пространство имен и его space в Tarantool
+func (kv *KeyValueManager) CreateNamespace(ctx context.Context, name string) (*models.Namespace, error) {
+	log.LogDebug(ctx, "Start creating namespace", logrus.Fields{"namespace": name})
+	if err := ValidateNamespace(name); err != nil {
+		return nil, err
 	}
 
-	resp, err := kv.tConn.Call("delete_kv", []interface{}{key})
+	resp, err := kv.call(ctx, "create_namespace_kv", []interface{}{name})
 	if err != nil {
-		logger.LogError("Failed to delete key", err, logrus.Fields{"key": key})
-		return nil, fmt.Errorf("failed to delete key: %w", err)
+		err = wrapTarantoolError("failed to create namespace", err)
+		if errors.Is(err, ErrAlreadyExists) {
+			log.LogInfo(ctx, "Namespace already exists", logrus.Fields{"namespace": name})
+			return nil, fmt.Errorf("failed to create namespace %q: %w", name, ErrNamespaceExists)
+		}
+		log.LogError(ctx, "Failed to create namespace", err, logrus.Fields{"namespace": name})
+		return nil, err
+	}
+
+	namespace, err := decodeNamespace(firstTuple(resp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create namespace: %w", err)
+	}
+	log.LogInfo(ctx, "Namespace successfully created", logrus.Fields{"namespace": name})
+	return namespace, nil
+}
+
+// ListNamespaces возвращает все пространства имен из реестра kv_namespaces
+func (kv *KeyValueManager) ListNamespaces(ctx context.Context) ([]*models.Namespace, error) {
+	resp, err := kv.call17(ctx, "list_namespaces_kv", []interface{}{})
+	if err != nil {
+		log.LogError(ctx, "Failed to list namespaces", err, nil)
+		return nil, wrapTarantoolError("failed to list namespaces", err)
 	}
 
-	if len(resp.Data) == 0 {
-		logger.LogInfo("Key not found during delete", logrus.Fields{"key": key})
-		return nil, fmt.Errorf("key not found")
+	var tuples []interface{}
+	if len(resp.Data) > 0 {
+		tuples, _ = resp.Data[0].([]interface{})
 	}
 
-	logger.LogInfo("Key successfully deleted", logrus.Fields{"key": key})
-	return existing, nil
+	namespaces := make([]*models.Namespace, 0, len(tuples))
+	for _, raw := range tuples {
+		tuple, _ := raw.([]interface{})
+		namespace, err := decodeNamespace(tuple)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, nil
 }
 
-// Update обновляет значение для ключа
-func (kv *KeyValueManager) Update(in *models.KeyValue) (*models.KeyValue, error) {
-	logger.LogInfo("Start updating key-value", logrus.Fields{"key-value": in})
-	dataSerialized, err := json.Marshal(in.Value)
+// DeleteNamespace удаляет пространство имен вместе с его space
+func (kv *KeyValueManager) DeleteNamespace(ctx context.Context, name string) error {
+	log.LogDebug(ctx, "Start deleting namespace", logrus.Fields{"namespace": name})
+	if name == DefaultNamespace {
+		return ErrDefaultNamespace
+	}
+	if err := ValidateNamespace(name); err != nil {
+		return fmt.Errorf("failed to delete namespace: %w", ErrNamespaceNotFound)
+	}
+
+	resp, err := kv.call17(ctx, "drop_namespace_kv", []interface{}{name})
 	if err != nil {
-		logger.LogError("Data serialization failed during update", err, logrus.Fields{"key": in.Key})
-		return nil, fmt.Errorf("data serialization failed: %w", err)
+		log.LogError(ctx, "Failed to delete namespace", err, logrus.Fields{"namespace": name})
+		return wrapTarantoolError("failed to delete namespace", err)
+	}
+	// drop_namespace_kv возвращает nil, если пространства нет
+	if len(resp.Data) == 0 || resp.Data[0] == nil {
+		return fmt.Errorf("failed to delete namespace %q: %w", name, ErrNamespaceNotFound)
 	}
 
-	resp, err := kv.tConn.Call("update_kv", []interface{}{in.Key, string(dataSerialized)})
+	log.LogInfo(ctx, "Namespace successfully deleted", logrus.Fields{"namespace": name})
+	return nil
+}
+
+// call вызывает Lua-функцию Tarantool. Запрос отменяется вместе с ctx,
+// а при истечении дедлайна возвращается ctx.Err(), чтобы вызывающий код
+// мог отличить таймаут от прочих ошибок через errors.Is.
+func (kv *KeyValueManager) call(ctx context.Context, function string, args []interface{}) (*tarantool.Response, error) {
+	ctx, span := kv.startSpan(ctx, function)
+	defer span.End()
+	return kv.do(ctx, span, tarantool.NewCallRequest(function).Args(args).Context(ctx))
+}
+
+// call17 работает как call, но использует протокол CALL 1.7,
+// в котором результат функции возвращается без преобразования в кортежи
+func (kv *KeyValueManager) call17(ctx context.Context, function string, args []interface{}) (*tarantool.Response, error) {
+	ctx, span := kv.startSpan(ctx, function)
+	defer span.End()
+	return kv.do(ctx, span, tarantool.NewCall17Request(function).Args(args).Context(ctx))
+}
+
+// startSpan создает клиентский спан вызова Lua-функции function
+func (kv *KeyValueManager) startSpan(ctx context.Context, function string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, function,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String("tarantool"),
+			semconv.DBOperationName(function),
+			semconv.DBNamespace(kv.namespace),
+		),
+	)
+}
+
+func (kv *KeyValueManager) do(ctx context.Context, span trace.Span, req tarantool.Request) (*tarantool.Response, error) {
+	resp, err := kv.tConn.Do(req).Get()
+	if err != nil && ctx.Err() != nil {
+		resp, err = nil, ctx.Err()
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return resp, err
+}
+
+// firstTuple возвращает первый кортеж ответа CALL 1.6 или nil,
+// если функция вернула nil вместо кортежа
+func firstTuple(resp *tarantool.Response) []interface{} {
+	if resp == nil || len(resp.Data) == 0 {
+		return nil
+	}
+
+	tuple, ok := resp.Data[0].([]interface{})
+	if !ok || len(tuple) == 0 || tuple[0] == nil {
+		return nil
+	}
+	return tuple
+}
+
+// decodeTuple преобразует кортеж {key, value, version, expires_at, created_at,
+// updated_at, labels} из space kv в модель
+func decodeTuple(tuple []interface{}) (*models.KeyValue, error) {
+	if len(tuple) < 2 {
+		return nil, fmt.Errorf("unexpected tuple length %d", len(tuple))
+	}
+
+	key, ok := tuple[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected key type %T", tuple[0])
+	}
+	value, err := decodeObject(tuple[1])
 	if err != nil {
-		logger.LogError("Failed to update key", err, logrus.Fields{"key": in.Key})
-		return nil, fmt.Errorf("failed to update key: %w", err)
+		return nil, fmt.Errorf("failed to deserialize value: %w", err)
+	}
+
+	// Кортежи, записанные до появления версий, имеют версию 0
+	var version uint64
+	if len(tuple) > 2 && tuple[2] != nil {
+		if version, ok = toUint64(tuple[2]); !ok {
+			return nil, fmt.Errorf("unexpected version type %T", tuple[2])
+		}
+	}
+
+	var expiresAt uint64
+	if len(tuple) > 3 && tuple[3] != nil {
+		if expiresAt, ok = toUint64(tuple[3]); !ok {
+			return nil, fmt.Errorf("unexpected expires_at type %T", tuple[3])
+		}
 	}
 
-	data := resp.Data[0].([]interface{})
-	if data[0] == nil {
-		logger.LogInfo("Key not found during update", logrus.Fields{"key": in.Key})
-		return nil, fmt.Errorf("key not found")
+	createdAt, err := decodeTime(tuple, 4, "created_at")
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := decodeTime(tuple, 5, "updated_at")
+	if err != nil {
+		return nil, err
+	}
+	var labels map[string]string
+	if len(tuple) > 6 {
+		if labels, err = decodeLabels(tuple[6]); err != nil {
+			return nil, err
+		}
 	}
 
-	logger.LogInfo("Key successfully updated", logrus.Fields{"key": in.Key})
-	return in, nil
+	item := &models.KeyValue{Key: key, Value: value, Version: version, Labels: labels}
+	setExpiry(item, int64(expiresAt), time.Now())
+	setTimestamps(item, createdAt, updatedAt)
+	return item, nil
+}
+
+// decodeTime читает необязательное поле кортежа с временем в секундах Unix.
+// Отсутствующее поле дает нулевое время.
+func decodeTime(tuple []interface{}, idx int, name string) (time.Time, error) {
+	if len(tuple) <= idx || tuple[idx] == nil {
+		return time.Time{}, nil
+	}
+	seconds, ok := toUint64(tuple[idx])
+	if !ok {
+		return time.Time{}, fmt.Errorf("unexpected %s type %T", name, tuple[idx])
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
+
+// decodeLabels приводит map меток, декодированный из msgpack, к map[string]string
+func decodeLabels(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected labels type %T", raw)
+	}
+
+	labels := make(map[string]string, len(m))
+	for k, v := range m {
+		key, okKey := k.(string)
+		value, okValue := v.(string)
+		if !okKey || !okValue {
+			return nil, fmt.Errorf("unexpected label %v=%v", k, v)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// decodeNamespace преобразует кортеж {name, created_at} из space kv_namespaces в модель
+func decodeNamespace(tuple []interface{}) (*models.Namespace, error) {
+	if len(tuple) < 2 {
+		return nil, fmt.Errorf("unexpected namespace tuple length %d", len(tuple))
+	}
+
+	name, ok := tuple[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected namespace name type %T", tuple[0])
+	}
+	createdAt, ok := toUint64(tuple[1])
+	if !ok {
+		return nil, fmt.Errorf("unexpected created_at type %T", tuple[1])
+	}
+	return &models.Namespace{Name: name, CreatedAt: time.Unix(int64(createdAt), 0).UTC()}, nil
+}
+
+// toUint64 приводит целое число, декодированное из msgpack, к uint64
+func toUint64(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return n, true
+	case uint32:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint8:
+		return uint64(n), true
+	case uint:
+		return uint64(n), true
+	case int64:
+		return uint64(n), n >= 0
+	case int32:
+		return uint64(n), n >= 0
+	case int16:
+		return uint64(n), n >= 0
+	case int8:
+		return uint64(n), n >= 0
+	case int:
+		return uint64(n), n >= 0
+	default:
+		return 0, false
+	}
+}
+
+// decodeChange преобразует кортеж {revision, type, key, version, value, timestamp}
+// из space kv_changelog в модель
+func decodeChange(entry []interface{}) (*models.Change, error) {
+	if len(entry) < 6 {
+		return nil, fmt.Errorf("unexpected change length %d", len(entry))
+	}
+
+	revision, ok := toUint64(entry[0])
+	if !ok {
+		return nil, fmt.Errorf("unexpected revision type %T", entry[0])
+	}
+	eventType, ok := entry[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected change type %T", entry[1])
+	}
+	key, ok := entry[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected key type %T", entry[2])
+	}
+
+	var version uint64
+	if entry[3] != nil {
+		if version, ok = toUint64(entry[3]); !ok {
+			return nil, fmt.Errorf("unexpected version type %T", entry[3])
+		}
+	}
+
+	var value map[string]interface{}
+	if entry[4] != nil {
+		var err error
+		if value, err = decodeObject(entry[4]); err != nil {
+			return nil, fmt.Errorf("failed to deserialize value: %w", err)
+		}
+	}
+
+	timestamp, ok := toUint64(entry[5])
+	if !ok {
+		return nil, fmt.Errorf("unexpected timestamp type %T", entry[5])
+	}
+
+	return &models.Change{
+		Revision: revision,
+		Event: models.Event{
+			Type:    eventType,
+			Key:     key,
+			Version: version,
+			Value:   value,
+		},
+		Timestamp: time.Unix(int64(timestamp), 0).UTC(),
+	}, nil
+}
+
+// decodeHistoryTuple преобразует кортеж {key, version, value, timestamp}
+// из space kv_history в модель
+func decodeHistoryTuple(tuple []interface{}) (*models.KeyValue, error) {
+	if len(tuple) < 3 {
+		return nil, fmt.Errorf("unexpected history tuple length %d", len(tuple))
+	}
+
+	key, ok := tuple[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected key type %T", tuple[0])
+	}
+	version, ok := toUint64(tuple[1])
+	if !ok {
+		return nil, fmt.Errorf("unexpected version type %T", tuple[1])
+	}
+	value, err := decodeObject(tuple[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize value: %w", err)
+	}
+	// Время записи версии отдается как время ее изменения
+	updatedAt, err := decodeTime(tuple, 3, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.KeyValue{Key: key, Value: value, Version: version}
+	setTimestamps(item, time.Time{}, updatedAt)
+	return item, nil
+}
+
+// decodeTrashTuple преобразует кортеж {key, value, version, deleted_at,
+// labels, created_at} из space kv_trash в модель
+func decodeTrashTuple(tuple []interface{}) (*models.TrashedKeyValue, error) {
+	if len(tuple) < 4 {
+		return nil, fmt.Errorf("unexpected trash tuple length %d", len(tuple))
+	}
+
+	key, ok := tuple[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected key type %T", tuple[0])
+	}
+	value, err := decodeObject(tuple[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize value: %w", err)
+	}
+	version, ok := toUint64(tuple[2])
+	if !ok {
+		return nil, fmt.Errorf("unexpected version type %T", tuple[2])
+	}
+	deletedAt, ok := toUint64(tuple[3])
+	if !ok {
+		return nil, fmt.Errorf("unexpected deleted_at type %T", tuple[3])
+	}
+	var labels map[string]string
+	if len(tuple) > 4 {
+		if labels, err = decodeLabels(tuple[4]); err != nil {
+			return nil, err
+		}
+	}
+	createdAt, err := decodeTime(tuple, 5, "created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	trashed := &models.TrashedKeyValue{
+		KeyValue:  models.KeyValue{Key: key, Value: value, Version: version, Labels: labels},
+		DeletedAt: time.Unix(int64(deletedAt), 0).UTC(),
+	}
+	setTimestamps(&trashed.KeyValue, createdAt, time.Time{})
+	return trashed, nil
 }
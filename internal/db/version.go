@@ -0,0 +1,30 @@
+package db
+
+import "math"
+
+// Unversioned - значение ifVersion, которое требует, чтобы у ключа не было
+// версии, то есть он был записан до появления версий и с тех пор не менялся.
+// Такие ключи отдаются с версией 0, но 0 в ifVersion отключает проверку,
+// поэтому их версия передается отдельным значением.
+const Unversioned uint64 = math.MaxUint64
+
+// ExpectVersion возвращает ifVersion, который требует текущую версию version,
+// в том числе для ключа без версии
+func ExpectVersion(version uint64) uint64 {
+	if version == 0 {
+		return Unversioned
+	}
+	return version
+}
+
+// versionMatches сообщает, подходит ли текущая версия ключа под ifVersion
+func versionMatches(current, ifVersion uint64) bool {
+	switch ifVersion {
+	case 0:
+		return true
+	case Unversioned:
+		return current == 0
+	default:
+		return current == ifVersion
+	}
+}
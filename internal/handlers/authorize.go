@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// WithPolicy включает проверку прав клиента по ролям перед каждым обращением
+// к хранилищу. Разрешения из токена клиента проверяются и без политики.
+func WithPolicy(policy *auth.Policy) Option {
+	return func(h *Handler) {
+		h.policy = policy
+	}
+}
+
+// namespaceName возвращает имя пространства имен запроса
+func namespaceName(c *gin.Context) string {
+	if name := c.Param("ns"); name != "" {
+		return name
+	}
+	return db.DefaultNamespace
+}
+
+// authorize проверяет, что клиенту разрешены все действия verbs с ключом key,
+// и отвечает 403 с причиной отказа
+func (h *Handler) authorize(c *gin.Context, key string, verbs ...auth.Verb) bool {
+	if err := h.checkKey(c, key, verbs...); err != nil {
+		forbid(c, err)
+		return false
+	}
+	return true
+}
+
+// checkKey возвращает причину отказа в действиях verbs с ключом key или nil
+func (h *Handler) checkKey(c *gin.Context, key string, verbs ...auth.Verb) error {
+	principal := auth.FromContext(c.Request.Context())
+	for _, verb := range verbs {
+		if err := h.policy.Authorize(principal, verb, namespaceName(c), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorizePrefix работает как authorize для всех ключей с префиксом prefix
+func (h *Handler) authorizePrefix(c *gin.Context, prefix string, verb auth.Verb) bool {
+	principal := auth.FromContext(c.Request.Context())
+	if err := h.policy.AuthorizePrefix(principal, verb, namespaceName(c), prefix); err != nil {
+		forbid(c, err)
+		return false
+	}
+	return true
+}
+
+// authorizeNamespace проверяет право управлять пространством имен name:
+// действие admin со всеми ключами пространства
+func (h *Handler) authorizeNamespace(c *gin.Context, name string) bool {
+	principal := auth.FromContext(c.Request.Context())
+	if err := h.policy.AuthorizePrefix(principal, auth.VerbAdmin, name, ""); err != nil {
+		forbid(c, err)
+		return false
+	}
+	return true
+}
+
+// authorizeServer проверяет право admin над всем сервером, см. auth.Policy.AuthorizeServer
+func (h *Handler) authorizeServer(c *gin.Context) bool {
+	principal := auth.FromContext(c.Request.Context())
+	if err := h.policy.AuthorizeServer(principal, auth.VerbAdmin); err != nil {
+		forbid(c, err)
+		return false
+	}
+	return true
+}
+
+func forbid(c *gin.Context, err error) {
+	log.LogInfo(c.Request.Context(), "Request forbidden", logrus.Fields{"reason": err.Error()})
+	c.JSON(http.StatusForbidden, models.Response{
+		Error: err.Error(),
+	})
+}
+
+// putVerbs возвращает действия, которые может выполнить PUT в режиме mode
+func putVerbs(mode db.PutMode) []auth.Verb {
+	switch mode {
+	case db.PutCreate:
+		return []auth.Verb{auth.VerbCreate}
+	case db.PutReplace:
+		return []auth.Verb{auth.VerbUpdate}
+	default:
+		return []auth.Verb{auth.VerbCreate, auth.VerbUpdate}
+	}
+}
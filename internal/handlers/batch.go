@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// BatchKeyValues выполняет несколько операций с ключами одним запросом.
+// Ответ содержит статус и результат каждой операции в порядке запроса.
+// Если атомарный пакет откатился, возвращается 409, а операции,
+// изменения которых были отменены, получают статус 424.
+func (h *Handler) BatchKeyValues(c *gin.Context) {
+	var request models.BatchRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.LogError(c.Request.Context(), "Invalid request body", err, logrus.Fields{"body": c.Request.Body})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid body",
+		})
+		return
+	}
+
+	if msg := validateBatch(request.Operations); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"operations": len(request.Operations)})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
+		})
+		return
+	}
+
+	// Имена операций пакета совпадают с действиями политики
+	for i, op := range request.Operations {
+		if err := h.checkKey(c, op.Key, auth.Verb(op.Op)); err != nil {
+			forbid(c, fmt.Errorf("operation %d: %w", i, err))
+			return
+		}
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Batch)
+	defer cancel()
+
+	results, err := h.storageFor(c).Batch(ctx, request.Operations, request.Atomic)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error executing batch", err, logrus.Fields{"operations": len(request.Operations)})
+		respondStorageError(c, err)
+		return
+	}
+
+	items := make([]models.BatchItemResult, len(results))
+	failed := false
+	for i, result := range results {
+		if result.Err != nil {
+			failed = failed || db.BatchFailed(request.Operations[i], result.Err)
+			status, message := batchErrorStatus(result.Err)
+			items[i] = models.BatchItemResult{Status: status, Error: message}
+			continue
+		}
+		items[i] = models.BatchItemResult{Status: http.StatusOK, Result: result.Item}
+	}
+
+	if request.Atomic && failed {
+		log.LogInfo(c.Request.Context(), "Batch rolled back", logrus.Fields{"operations": len(request.Operations)})
+		c.JSON(http.StatusConflict, models.Response{
+			Result: items,
+			Error:  "Batch rolled back",
+		})
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Batch executed successfully", logrus.Fields{"operations": len(request.Operations)})
+	c.JSON(http.StatusOK, models.Response{
+		Result:  items,
+		Message: "Batch executed successfully",
+	})
+}
+
+// validateBatch проверяет операции пакета и возвращает текст ошибки или ""
+func validateBatch(ops []models.BatchOperation) string {
+	if len(ops) == 0 {
+		return "Operations are required"
+	}
+	if len(ops) > db.MaxBatchSize {
+		return fmt.Sprintf("Too many operations, maximum is %d", db.MaxBatchSize)
+	}
+
+	for i := range ops {
+		op := &ops[i]
+		if msg := validateBatchOperation(op); msg != "" {
+			return fmt.Sprintf("Operation %d: %s", i, msg)
+		}
+	}
+	return ""
+}
+
+func validateBatchOperation(op *models.BatchOperation) string {
+	switch op.Op {
+	case models.BatchGet, models.BatchDelete:
+	case models.BatchCreate, models.BatchUpdate:
+		if len(op.Value) == 0 {
+			return "Value must be a non-empty object"
+		}
+	default:
+		return fmt.Sprintf("Unknown operation %q", op.Op)
+	}
+
+	if op.Key == "" {
+		return "Key is required"
+	}
+
+	ttl := models.KeyValue{TTL: op.TTL, ExpiresAt: op.ExpiresAt}
+	if msg := applyTTL(&ttl); msg != "" {
+		return msg
+	}
+	op.ExpiresAt = ttl.ExpiresAt
+	return validateLabels(op.Labels)
+}
+
+// batchErrorStatus возвращает статус и текст ошибки одной операции пакета
+func batchErrorStatus(err error) (int, string) {
+	if errors.Is(err, db.ErrBatchAborted) {
+		return http.StatusFailedDependency, "Rolled back"
+	}
+	return storageErrorStatus(err)
+}
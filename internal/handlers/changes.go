@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListChanges возвращает страницу журнала изменений после ревизии since.
+// Поле revision ответа нужно передать в since, чтобы получить следующую страницу.
+// Если записи после since уже удалены компакцией, возвращается 410.
+// since=now возвращает без записей текущую ревизию журнала.
+func (h *Handler) ListChanges(c *gin.Context) {
+	var opts db.ChangesOptions
+
+	if rawSince := c.Query("since"); rawSince == "now" {
+		opts.FromHead = true
+	} else if rawSince != "" {
+		since, err := strconv.ParseUint(rawSince, 10, 64)
+		if err != nil {
+			log.LogInfo(c.Request.Context(), "Invalid changes revision", logrus.Fields{"since": rawSince})
+			c.JSON(http.StatusBadRequest, models.Response{
+				Error: "since must be a non-negative integer or now",
+			})
+			return
+		}
+		opts.Since = since
+	}
+
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			log.LogInfo(c.Request.Context(), "Invalid changes limit", logrus.Fields{"limit": rawLimit})
+			c.JSON(http.StatusBadRequest, models.Response{
+				Error: "Limit must be a positive integer",
+			})
+			return
+		}
+		opts.Limit = limit
+	}
+
+	// Журнал содержит изменения всех ключей пространства имен
+	if !h.authorizePrefix(c, "", auth.VerbGet) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.List)
+	defer cancel()
+
+	changes, revision, err := h.storageFor(c).Changes(ctx, opts)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error reading changes", err, logrus.Fields{"since": opts.Since})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Listed changes successfully", logrus.Fields{"since": opts.Since, "count": len(changes)})
+	c.JSON(http.StatusOK, models.Response{
+		Result:   changes,
+		Revision: revision,
+		Message:  "Changes listed successfully",
+	})
+}
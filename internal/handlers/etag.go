@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// etag формирует сильный ETag из версии ключа
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseIfMatch возвращает ожидаемую версию из заголовка If-Match.
+// Пустой заголовок и "*" не ограничивают версию и возвращают 0.
+// Поддерживается только один сильный ETag, выданный этим сервером.
+// ETag "0" ключа, записанного до появления версий, дает db.Unversioned.
+func parseIfMatch(header string) (uint64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+
+	version, err := strconv.ParseUint(header[1:len(header)-1], 10, 64)
+	if err != nil || version == db.Unversioned {
+		return 0, errInvalidIfMatch
+	}
+	return db.ExpectVersion(version), nil
+}
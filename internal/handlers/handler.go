@@ -1,24 +1,116 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
 	"github.com/MosinFAM/tarantool-kv/internal/db"
 	"github.com/MosinFAM/tarantool-kv/internal/logger"
 	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/MosinFAM/tarantool-kv/internal/patch"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// log пишет логи пакета с уровнем "handlers", см. logger.SetPackageLevel
+var log = logger.Package("handlers")
+
 const keyNotFoundError = "key not found"
 
+// statusClientClosedRequest - нестандартный код (как в nginx) для запросов,
+// клиент которых отключился до получения ответа
+const statusClientClosedRequest = 499
+
+// Timeouts задает дедлайн по умолчанию для каждой операции с хранилищем.
+// Нулевое значение означает, что операция ограничена только контекстом запроса.
+type Timeouts struct {
+	Create time.Duration
+	Get    time.Duration
+	Update time.Duration
+	Delete time.Duration
+	List   time.Duration
+	Batch  time.Duration
+}
+
+// DefaultTimeouts возвращает дедлайны, которые используются, если не заданы свои
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Create: 5 * time.Second,
+		Get:    5 * time.Second,
+		Update: 5 * time.Second,
+		Delete: 5 * time.Second,
+		List:   5 * time.Second,
+		Batch:  10 * time.Second,
+	}
+}
+
 type Handler struct {
-	storage db.Storage
+	// storage - хранилище пространства имен по умолчанию, с которым
+	// работают маршруты /kv
+	storage     db.Storage
+	timeouts    Timeouts
+	broadcaster *watch.Broadcaster
+	// namespaces и broadcasters обслуживают маршруты /ns/:ns
+	namespaces   db.Namespaces
+	broadcasters Broadcasters
+	// policy - права клиентов, nil разрешает все
+	policy *auth.Policy
 }
 
-func NewHandler(storage db.Storage) *Handler {
-	return &Handler{storage: storage}
+// Option настраивает Handler при создании
+type Option func(*Handler)
+
+// WithTimeouts задает дедлайны операций с хранилищем
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(h *Handler) {
+		h.timeouts = timeouts
+	}
+}
+
+// WithBroadcaster включает подписку на изменения ключей через WatchKeyValues
+func WithBroadcaster(b *watch.Broadcaster) Option {
+	return func(h *Handler) {
+		h.broadcaster = b
+	}
+}
+
+// WithNamespaces включает маршруты /ns/:ns и управление пространствами имен
+func WithNamespaces(namespaces db.Namespaces) Option {
+	return func(h *Handler) {
+		h.namespaces = namespaces
+	}
+}
+
+// WithBroadcasters включает подписку на изменения в пространствах имен
+func WithBroadcasters(b Broadcasters) Option {
+	return func(h *Handler) {
+		h.broadcasters = b
+	}
+}
+
+func NewHandler(storage db.Storage, opts ...Option) *Handler {
+	h := &Handler{storage: storage, timeouts: DefaultTimeouts()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// storageContext возвращает контекст запроса, ограниченный дедлайном операции
+func storageContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(c.Request.Context())
+	}
+	return context.WithTimeout(c.Request.Context(), timeout)
 }
 
 // CreateKeyValue создает новый ключ-значение
@@ -26,7 +118,7 @@ func (h *Handler) CreateKeyValue(c *gin.Context) {
 	var request models.KeyValue
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		logger.LogError("Invalid request body", err, logrus.Fields{"body": c.Request.Body})
+		log.LogError(c.Request.Context(), "Invalid request body", err, logrus.Fields{"body": c.Request.Body})
 		c.JSON(http.StatusBadRequest, models.Response{
 			Error: "Invalid body",
 		})
@@ -34,7 +126,7 @@ func (h *Handler) CreateKeyValue(c *gin.Context) {
 	}
 
 	if request.Key == "" {
-		logger.LogInfo("Key is required", logrus.Fields{"key": request.Key})
+		log.LogInfo(c.Request.Context(), "Key is required", logrus.Fields{"key": request.Key})
 		c.JSON(http.StatusBadRequest, models.Response{
 			Error: "Key is required",
 		})
@@ -43,95 +135,163 @@ func (h *Handler) CreateKeyValue(c *gin.Context) {
 
 	// Проверка на пустое значение
 	if len(request.Value) == 0 {
-		logger.LogInfo("Value must be a non-empty object", logrus.Fields{"key": request.Key})
+		log.LogInfo(c.Request.Context(), "Value must be a non-empty object", logrus.Fields{"key": request.Key})
 		c.JSON(http.StatusBadRequest, models.Response{
 			Error: "Value must be a non-empty object",
 		})
 		return
 	}
 
-	createdItem, err := h.storage.Create(&request)
-	if err != nil {
-		if err.Error() == "key already exists" {
-			logger.LogError("Key already exists", err, logrus.Fields{"key": request.Key})
-			c.JSON(http.StatusConflict, models.Response{
-				Error: "Key already exists",
-			})
-			return
-		}
+	if msg := applyTTL(&request); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"key": request.Key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
+		})
+		return
+	}
 
-		logger.LogError("Error creating key", err, logrus.Fields{"key": request.Key})
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Error: "Internal server error",
+	if msg := validateLabels(request.Labels); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"key": request.Key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
 		})
 		return
 	}
 
-	logger.LogInfo("Created key successfully", logrus.Fields{"key": request.Key})
+	if !h.authorize(c, request.Key, auth.VerbCreate) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Create)
+	defer cancel()
+
+	createdItem, err := h.storageFor(c).Create(ctx, &request)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error creating key", err, logrus.Fields{"key": request.Key})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Created key successfully", logrus.Fields{"key": request.Key})
+	c.Header("ETag", etag(createdItem.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Result:  createdItem,
 		Message: "Key created successfully",
 	})
 }
 
-// GetKeyValue получает значение для ключа
+// GetKeyValue получает значение для ключа.
+// С параметром version возвращается эта версия ключа из истории.
 func (h *Handler) GetKeyValue(c *gin.Context) {
 	key := c.Param("id")
 
-	gettedItem, err := h.storage.Get(key)
-	if err != nil {
-		logger.LogError("Error getting key", err, logrus.Fields{"key": key})
-		if err.Error() == keyNotFoundError {
-			c.JSON(http.StatusNotFound, models.Response{
-				Error: keyNotFoundError,
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, models.Response{
-				Error: "Internal server error",
-			})
+	var version uint64
+	if rawVersion := c.Query("version"); rawVersion != "" {
+		var ok bool
+		if version, ok = parseVersion(c, rawVersion); !ok {
+			return
 		}
+	}
+
+	if !h.authorize(c, key, auth.VerbGet) {
 		return
 	}
 
-	logger.LogInfo("Fetched key successfully", logrus.Fields{"key": key})
+	ctx, cancel := storageContext(c, h.timeouts.Get)
+	defer cancel()
+
+	var gettedItem *models.KeyValue
+	var err error
+	if version != 0 {
+		gettedItem, err = h.storageFor(c).GetVersion(ctx, key, version)
+	} else {
+		gettedItem, err = h.storageFor(c).Get(ctx, key)
+	}
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error getting key", err, logrus.Fields{"key": key})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Fetched key successfully", logrus.Fields{"key": key})
+	c.Header("ETag", etag(gettedItem.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Result:  gettedItem,
 		Message: "Key getted successfully",
 	})
 }
 
-// DeleteKeyValue удаляет ключ
+// ListKeyValues возвращает страницу ключей с заданным префиксом
+func (h *Handler) ListKeyValues(c *gin.Context) {
+	opts, ok := listOptions(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorizePrefix(c, opts.Prefix, auth.VerbGet) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.List)
+	defer cancel()
+
+	items, nextCursor, err := h.storageFor(c).List(ctx, opts)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error listing keys", err, logrus.Fields{"prefix": opts.Prefix})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Listed keys successfully", logrus.Fields{"prefix": opts.Prefix, "count": len(items)})
+	c.JSON(http.StatusOK, models.Response{
+		Result:     items,
+		NextCursor: nextCursor,
+		Message:    "Keys listed successfully",
+	})
+}
+
+// DeleteKeyValue удаляет ключ.
+// С заголовком If-Match ключ удаляется, только если его версия не изменилась.
 func (h *Handler) DeleteKeyValue(c *gin.Context) {
 	key := c.Param("id")
 
-	deletedItem, err := h.storage.Delete(key)
-	if err != nil {
-		logger.LogError("Error deleting key", err, logrus.Fields{"key": key})
-		if err.Error() == keyNotFoundError {
-			c.JSON(http.StatusNotFound, models.Response{
-				Error: keyNotFoundError,
-			})
-			return
-		}
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Error: "Internal server error",
-		})
+	if !h.authorize(c, key, auth.VerbDelete) {
 		return
 	}
 
-	logger.LogInfo("Deleted key successfully", logrus.Fields{"key": key})
+	ctx, cancel := storageContext(c, h.timeouts.Delete)
+	defer cancel()
+
+	deletedItem, err := h.storageFor(c).Delete(ctx, key, ifVersion)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error deleting key", err, logrus.Fields{"key": key})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Deleted key successfully", logrus.Fields{"key": key})
+	// ETag и тело ответа описывают удаленную версию ключа
+	c.Header("ETag", etag(deletedItem.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Deleted: deletedItem,
 		Message: "Key deleted successfully",
 	})
 }
 
-// UpdateKeyValue обновляет значение для ключа
+// UpdateKeyValue записывает значение ключа в режиме из параметра mode:
+// replace (по умолчанию) обновляет существующий ключ, create только создает,
+// upsert создает или обновляет. Созданный ключ возвращается со статусом 201.
+// С заголовком If-Match значение записывается, только если версия ключа не изменилась.
+// TTL заменяется вместе со значением: без ttl и expires_at ключ становится бессрочным.
 func (h *Handler) UpdateKeyValue(c *gin.Context) {
 	var request models.KeyValue
 	if err := c.ShouldBindJSON(&request); err != nil {
-		logger.LogError("Invalid request body", err, logrus.Fields{"body": c.Request.Body})
+		log.LogError(c.Request.Context(), "Invalid request body", err, logrus.Fields{"body": c.Request.Body})
 		c.JSON(http.StatusBadRequest, models.Response{
 			Error: "Invalid body",
 		})
@@ -141,25 +301,308 @@ func (h *Handler) UpdateKeyValue(c *gin.Context) {
 	key := c.Param("id")
 	request.Key = key
 
-	updatedItem, err := h.storage.Update(&request)
+	if len(request.Value) == 0 {
+		log.LogInfo(c.Request.Context(), "Value must be a non-empty object", logrus.Fields{"key": key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Value must be a non-empty object",
+		})
+		return
+	}
+
+	mode := db.PutMode(c.DefaultQuery("mode", string(db.PutReplace)))
+	if !mode.Valid() {
+		log.LogInfo(c.Request.Context(), "Invalid put mode", logrus.Fields{"key": key, "mode": mode})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "mode must be one of upsert, create, replace",
+		})
+		return
+	}
+
+	if msg := applyTTL(&request); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"key": key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
+		})
+		return
+	}
+
+	if msg := validateLabels(request.Labels); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"key": key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
+		})
+		return
+	}
+
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if mode == db.PutCreate && ifVersion != 0 {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "If-Match cannot be used with mode=create",
+		})
+		return
+	}
+
+	if !h.authorize(c, key, putVerbs(mode)...) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Update)
+	defer cancel()
+
+	item, created, err := h.storageFor(c).Put(ctx, &request, mode, ifVersion)
 	if err != nil {
-		logger.LogError("Error updating key", err, logrus.Fields{"key": key})
-		if err.Error() == keyNotFoundError {
-			c.JSON(http.StatusNotFound, models.Response{
-				Error: keyNotFoundError,
-			})
-			return
-		}
+		log.LogError(c.Request.Context(), "Error updating key", err, logrus.Fields{"key": key, "mode": mode})
+		respondStorageError(c, err)
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Error: "Internal server error",
+	c.Header("ETag", etag(item.Version))
+	if created {
+		log.LogInfo(c.Request.Context(), "Created key successfully", logrus.Fields{"key": key, "mode": mode})
+		c.JSON(http.StatusCreated, models.Response{
+			Result:  item,
+			Message: "Key created successfully",
 		})
 		return
 	}
 
-	logger.LogInfo("Updated key successfully", logrus.Fields{"key": key})
+	log.LogInfo(c.Request.Context(), "Updated key successfully", logrus.Fields{"key": key, "mode": mode})
 	c.JSON(http.StatusOK, models.Response{
-		Result:  updatedItem,
+		Result:  item,
 		Message: "Key updated successfully",
 	})
 }
+
+// maxTTLSeconds - наибольший TTL, который помещается в time.Duration
+const maxTTLSeconds = math.MaxInt64 / int64(time.Second)
+
+// PatchKeyValue частично изменяет значение ключа.
+// Поддерживаются JSON Merge Patch (application/merge-patch+json)
+// и JSON Patch (application/json-patch+json).
+// Патч применяется под проверкой версии, поэтому не теряет конкурентные записи.
+func (h *Handler) PatchKeyValue(c *gin.Context) {
+	key := c.Param("id")
+	contentType := c.ContentType()
+
+	if contentType != patch.MergePatchContentType && contentType != patch.JSONPatchContentType {
+		log.LogInfo(c.Request.Context(), "Unsupported patch content type", logrus.Fields{"key": key, "content-type": contentType})
+		c.JSON(http.StatusUnsupportedMediaType, models.Response{
+			Error: "Unsupported patch content type",
+		})
+		return
+	}
+
+	rawPatch, err := c.GetRawData()
+	if err != nil {
+		log.LogError(c.Request.Context(), "Invalid request body", err, logrus.Fields{"key": key})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid body",
+		})
+		return
+	}
+
+	// Патч разбирается заранее, чтобы не читать ключ ради некорректного тела
+	var apply db.ModifyFunc
+	if contentType == patch.MergePatchContentType {
+		p, err := patch.ParseMergePatch(rawPatch)
+		if err != nil {
+			log.LogInfo(c.Request.Context(), "Invalid merge patch", logrus.Fields{"key": key, "error": err.Error()})
+			respondPatchError(c, err)
+			return
+		}
+		apply = func(value map[string]interface{}) (map[string]interface{}, error) {
+			return patch.ApplyMergePatch(value, p)
+		}
+	} else {
+		ops, err := patch.ParseJSONPatch(rawPatch)
+		if err != nil {
+			log.LogInfo(c.Request.Context(), "Invalid JSON patch", logrus.Fields{"key": key, "error": err.Error()})
+			respondPatchError(c, err)
+			return
+		}
+		apply = func(value map[string]interface{}) (map[string]interface{}, error) {
+			return patch.ApplyJSONPatch(value, ops)
+		}
+	}
+
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(c, key, auth.VerbGet, auth.VerbUpdate) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Update)
+	defer cancel()
+
+	patchedItem, err := db.Modify(ctx, h.storageFor(c), key, ifVersion, apply)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error patching key", err, logrus.Fields{"key": key})
+		respondPatchError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Patched key successfully", logrus.Fields{"key": key})
+	c.Header("ETag", etag(patchedItem.Version))
+	c.JSON(http.StatusOK, models.Response{
+		Result:  patchedItem,
+		Message: "Key patched successfully",
+	})
+}
+
+// respondPatchError отвечает на ошибки применения патча,
+// остальные ошибки передаются в respondStorageError
+func respondPatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, patch.ErrInvalidPatch):
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid patch",
+		})
+	case errors.Is(err, patch.ErrTestFailed):
+		c.JSON(http.StatusConflict, models.Response{
+			Error: "Patch test operation failed",
+		})
+	case errors.Is(err, patch.ErrInvalidPath):
+		c.JSON(http.StatusUnprocessableEntity, models.Response{
+			Error: "Invalid patch path",
+		})
+	case errors.Is(err, patch.ErrNotObject):
+		c.JSON(http.StatusUnprocessableEntity, models.Response{
+			Error: "Patched value must be a JSON object",
+		})
+	default:
+		respondStorageError(c, err)
+	}
+}
+
+// applyTTL переводит ttl из запроса в абсолютное время истечения expires_at.
+// Возвращает текст ошибки для клиента или пустую строку.
+func applyTTL(request *models.KeyValue) string {
+	now := time.Now()
+	switch {
+	case request.TTL < 0:
+		return "TTL must be a positive number of seconds"
+	case request.TTL > maxTTLSeconds:
+		return "TTL is too large"
+	case request.TTL > 0 && request.ExpiresAt != nil:
+		return "Only one of ttl and expires_at may be set"
+	case request.TTL > 0:
+		expiresAt := now.Add(time.Duration(request.TTL) * time.Second)
+		request.ExpiresAt = &expiresAt
+	case request.ExpiresAt != nil && !request.ExpiresAt.After(now):
+		return "expires_at must be in the future"
+	}
+	return ""
+}
+
+// validateLabels проверяет, что к каждой метке можно обратиться в селекторе.
+// Возвращает текст ошибки для клиента или пустую строку.
+func validateLabels(labels map[string]string) string {
+	for key := range labels {
+		if strings.TrimSpace(key) == "" {
+			return "Label key must not be empty"
+		}
+		if strings.ContainsAny(key, ",=!") || strings.TrimSpace(key) != key {
+			return fmt.Sprintf("Label key %q must not contain ',', '=', '!' or surrounding spaces", key)
+		}
+	}
+	return ""
+}
+
+// listOptions разбирает параметры листинга prefix, limit, cursor и labels.
+// При ошибке клиенту уже отправлен ответ 400.
+func listOptions(c *gin.Context) (db.ListOptions, bool) {
+	opts := db.ListOptions{
+		Prefix: c.Query("prefix"),
+		Cursor: c.Query("cursor"),
+	}
+
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			log.LogInfo(c.Request.Context(), "Invalid list limit", logrus.Fields{"limit": rawLimit})
+			c.JSON(http.StatusBadRequest, models.Response{
+				Error: "Limit must be a positive integer",
+			})
+			return opts, false
+		}
+		opts.Limit = limit
+	}
+
+	selector, err := db.ParseLabelSelector(c.Query("labels"))
+	if err != nil {
+		log.LogInfo(c.Request.Context(), "Invalid label selector", logrus.Fields{"labels": c.Query("labels")})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid label selector",
+		})
+		return opts, false
+	}
+	opts.Labels = selector
+	return opts, true
+}
+
+// ifMatchVersion разбирает If-Match и отвечает 412, если заголовок не может
+// совпасть ни с одной версией ключа
+func ifMatchVersion(c *gin.Context) (uint64, bool) {
+	ifVersion, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		log.LogInfo(c.Request.Context(), "Invalid If-Match header", logrus.Fields{"if-match": c.GetHeader("If-Match")})
+		c.JSON(http.StatusPreconditionFailed, models.Response{
+			Error: "Version mismatch",
+		})
+		return 0, false
+	}
+	return ifVersion, true
+}
+
+// respondStorageError отвечает клиенту статусом, соответствующим ошибке хранилища
+func respondStorageError(c *gin.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+
+	status, message := storageErrorStatus(err)
+	c.JSON(status, models.Response{
+		Error: message,
+	})
+}
+
+// storageErrorStatus возвращает HTTP-статус и текст ответа для ошибки хранилища
+func storageErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound, keyNotFoundError
+	case errors.Is(err, db.ErrVersionNotFound):
+		return http.StatusNotFound, "Version not found"
+	case errors.Is(err, db.ErrNamespaceNotFound):
+		return http.StatusNotFound, "Namespace not found"
+	case errors.Is(err, db.ErrInvalidNamespace):
+		return http.StatusBadRequest, "Namespace name must match [a-z0-9][a-z0-9_-]{0,62}"
+	case errors.Is(err, db.ErrDefaultNamespace):
+		return http.StatusBadRequest, "Default namespace cannot be deleted"
+	case errors.Is(err, db.ErrNamespaceExists):
+		return http.StatusConflict, "Namespace already exists"
+	case errors.Is(err, db.ErrInvalidCursor):
+		return http.StatusBadRequest, "Invalid cursor"
+	case errors.Is(err, db.ErrRevisionCompacted):
+		return http.StatusGone, "Revision is compacted, resync required"
+	case errors.Is(err, db.ErrAlreadyExists):
+		return http.StatusConflict, "Key already exists"
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict, "Concurrent modification, retry the request"
+	case errors.Is(err, db.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, "Version mismatch"
+	case errors.Is(err, db.ErrBackendUnavailable):
+		return http.StatusServiceUnavailable, "Storage unavailable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Storage timeout"
+	default:
+		return http.StatusInternalServerError, "Internal server error"
+	}
+}
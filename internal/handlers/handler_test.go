@@ -1,17 +1,26 @@
 package handlers_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
 	"github.com/MosinFAM/tarantool-kv/internal/db"
 	"github.com/MosinFAM/tarantool-kv/internal/handlers"
 	"github.com/MosinFAM/tarantool-kv/internal/logger"
 	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/mock/gomock"
@@ -50,7 +59,7 @@ func TestCreateKeyValue_Success(t *testing.T) {
 		Value: map[string]interface{}{"data": "testValue"},
 	}
 
-	mockStorage.EXPECT().Create(gomock.Any()).Return(&validRequest, nil)
+	mockStorage.EXPECT().Create(gomock.Any(), gomock.Any()).Return(&validRequest, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -77,7 +86,7 @@ func TestCreateKeyValue_Conflict(t *testing.T) {
 		Value: map[string]interface{}{"data": "testValue"},
 	}
 
-	mockStorage.EXPECT().Create(gomock.Any()).Return(nil, errors.New("key already exists"))
+	mockStorage.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, db.ErrAlreadyExists)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -104,7 +113,7 @@ func TestGetKeyValue_Success(t *testing.T) {
 		Value: map[string]interface{}{"data": "testValue"},
 	}
 
-	mockStorage.EXPECT().Get("testKey").Return(&validRequest, nil)
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&validRequest, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -122,7 +131,7 @@ func TestGetKeyValue_NotFound(t *testing.T) {
 	h, mockStorage, ctrl := setupTest(t)
 	defer ctrl.Finish()
 
-	mockStorage.EXPECT().Get("missingKey").Return(nil, errors.New("key not found"))
+	mockStorage.EXPECT().Get(gomock.Any(), "missingKey").Return(nil, db.ErrNotFound)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -145,7 +154,7 @@ func TestUpdateKeyValue_Success(t *testing.T) {
 		Value: map[string]interface{}{"data": "testValue"},
 	}
 
-	mockStorage.EXPECT().Update(gomock.Any()).Return(&validRequest, nil)
+	mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutReplace, uint64(0)).Return(&validRequest, false, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -168,7 +177,7 @@ func TestUpdateKeyValue_NotFound(t *testing.T) {
 	h, mockStorage, ctrl := setupTest(t)
 	defer ctrl.Finish()
 
-	mockStorage.EXPECT().Update(gomock.Any()).Return(nil, errors.New("key not found"))
+	mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutReplace, uint64(0)).Return(nil, false, db.ErrNotFound)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -197,7 +206,7 @@ func TestDeleteKeyValue_Success(t *testing.T) {
 		Value: map[string]interface{}{"data": "testValue"},
 	}
 
-	mockStorage.EXPECT().Delete(validKey).Return(&validRequest, nil)
+	mockStorage.EXPECT().Delete(gomock.Any(), validKey, uint64(0)).Return(&validRequest, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -217,7 +226,7 @@ func TestDeleteKeyValue_NotFound(t *testing.T) {
 
 	invalidKey := "missingKey"
 
-	mockStorage.EXPECT().Delete(invalidKey).Return(nil, errors.New("key not found"))
+	mockStorage.EXPECT().Delete(gomock.Any(), invalidKey, uint64(0)).Return(nil, db.ErrNotFound)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -342,7 +351,7 @@ func TestCreateKeyValue_InternalServerError(t *testing.T) {
 	}
 
 	// Смоделируем ошибку при создании
-	mockStorage.EXPECT().Create(gomock.Any()).Return(nil, errors.New("some internal error"))
+	mockStorage.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, errors.New("some internal error"))
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -400,3 +409,1194 @@ func TestCreateKeyValue_InvalidBody2(t *testing.T) {
 		t.Errorf("expected error message 'Invalid body', got '%s'", response.Error)
 	}
 }
+
+func TestGetKeyValue_WrappedNotFound(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	// Текст ошибки отличается от "key not found", но она оборачивает db.ErrNotFound
+	mockStorage.EXPECT().Get(gomock.Any(), "missingKey").Return(nil, fmt.Errorf("lookup failed: %w", db.ErrNotFound))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "missingKey"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/missingKey", nil)
+
+	h.GetKeyValue(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetKeyValue_BackendUnavailable(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(nil, fmt.Errorf("failed to get key: %w", db.ErrBackendUnavailable))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/testKey", nil)
+
+	h.GetKeyValue(c)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+}
+
+func TestGetKeyValue_StorageDeadline(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	h = handlers.NewHandler(mockStorage, handlers.WithTimeouts(handlers.Timeouts{Get: 10 * time.Millisecond}))
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").DoAndReturn(
+		func(ctx context.Context, _ string) (*models.KeyValue, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected storage context to have a deadline")
+			}
+			<-ctx.Done()
+			return nil, fmt.Errorf("failed to get key: %w", ctx.Err())
+		})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/testKey", nil)
+
+	h.GetKeyValue(c)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+}
+
+func TestListKeyValues_Success(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	items := []*models.KeyValue{
+		{Key: "app/a", Value: map[string]interface{}{"data": "a"}},
+		{Key: "app/b", Value: map[string]interface{}{"data": "b"}},
+	}
+	mockStorage.EXPECT().
+		List(gomock.Any(), db.ListOptions{Prefix: "app/", Limit: 2}).
+		Return(items, "next-page", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv?prefix=app/&limit=2", nil)
+
+	h.ListKeyValues(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response models.Response
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.NextCursor != "next-page" {
+		t.Errorf("expected next cursor 'next-page', got '%s'", response.NextCursor)
+	}
+}
+
+func TestListKeyValues_InvalidLimit(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv?limit=abc", nil)
+
+	h.ListKeyValues(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestListKeyValues_LabelSelector(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	expected := db.ListOptions{
+		Labels: db.LabelSelector{
+			{Key: "team", Op: "=", Value: "payments"},
+			{Key: "legacy", Op: "!exists"},
+		},
+	}
+	mockStorage.EXPECT().List(gomock.Any(), expected).Return(nil, "", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv?labels=team%3Dpayments,!legacy", nil)
+
+	h.ListKeyValues(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestListKeyValues_InvalidLabelSelector(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv?labels=%3Dpayments", nil)
+
+	h.ListKeyValues(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCreateKeyValue_InvalidLabelKey(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"key": "a", "value": {"n": 1}, "labels": {"a,b": "c"}}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateKeyValue(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestListKeyValues_InvalidCursor(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, "", db.ErrInvalidCursor)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv?cursor=broken", nil)
+
+	h.ListKeyValues(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestGetKeyValue_ETag(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"data": "testValue"},
+		Version: 3,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/testKey", nil)
+
+	h.GetKeyValue(c)
+
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Errorf(`expected ETag "3", got %s`, etag)
+	}
+}
+
+func TestUpdateKeyValue_IfMatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutReplace, uint64(3)).Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"data": "newValue"},
+		Version: 4,
+	}, false, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/testKey", bytes.NewBufferString(`{"value": {"data": "newValue"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"3"`)
+
+	h.UpdateKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"4"` {
+		t.Errorf(`expected ETag "4", got %s`, etag)
+	}
+}
+
+func TestUpdateKeyValue_EmptyValue(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	for _, body := range []string{`{}`, `{"value": {}}`} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/testKey", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.UpdateKeyValue(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestUpdateKeyValue_VersionMismatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutReplace, uint64(3)).Return(nil, false, db.ErrVersionMismatch)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/testKey", bytes.NewBufferString(`{"value": {"data": "newValue"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("If-Match", `"3"`)
+
+	h.UpdateKeyValue(c)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412, got %d", w.Code)
+	}
+}
+
+func TestDeleteKeyValue_InvalidIfMatch(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/testKey", nil)
+	c.Request.Header.Set("If-Match", `W/"3"`)
+
+	h.DeleteKeyValue(c)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412, got %d", w.Code)
+	}
+}
+
+func TestDeleteKeyValue_UnversionedIfMatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Delete(gomock.Any(), "testKey", db.Unversioned).Return(&models.KeyValue{
+		Key:   "testKey",
+		Value: map[string]interface{}{"data": "testValue"},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/testKey", nil)
+	c.Request.Header.Set("If-Match", `"0"`)
+
+	h.DeleteKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestCreateKeyValue_TTL(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *models.KeyValue) (*models.KeyValue, error) {
+			if in.ExpiresAt == nil {
+				t.Fatal("expected ttl to be converted to expires_at")
+			}
+			if remaining := time.Until(*in.ExpiresAt); remaining <= 0 || remaining > time.Minute {
+				t.Errorf("expected expires_at about a minute from now, got %v", remaining)
+			}
+			return in, nil
+		})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"key": "session", "value": {"user": "1"}, "ttl": 60}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestCreateKeyValue_InvalidTTL(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	for _, body := range []string{
+		`{"key": "session", "value": {"user": "1"}, "ttl": -1}`,
+		`{"key": "session", "value": {"user": "1"}, "expires_at": "2000-01-01T00:00:00Z"}`,
+		`{"key": "session", "value": {"user": "1"}, "ttl": 60, "expires_at": "2999-01-01T00:00:00Z"}`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.CreateKeyValue(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func newPatchContext(w *httptest.ResponseRecorder, key, contentType, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: key}}
+	c.Request = httptest.NewRequest(http.MethodPatch, "/"+key, bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", contentType)
+	return c
+}
+
+func TestPatchKeyValue_MergePatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"a": "b", "c": map[string]interface{}{"d": "e"}},
+		Version: 2,
+	}, nil)
+	mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), uint64(2)).DoAndReturn(
+		func(_ context.Context, in *models.KeyValue, _ uint64) (*models.KeyValue, error) {
+			expected := map[string]interface{}{"c": map[string]interface{}{"d": "e", "f": "g"}}
+			if !reflect.DeepEqual(in.Value, expected) {
+				t.Errorf("expected merged value %v, got %v", expected, in.Value)
+			}
+			return &models.KeyValue{Key: in.Key, Value: in.Value, Version: 3}, nil
+		})
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/merge-patch+json", `{"a": null, "c": {"f": "g"}}`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Errorf(`expected ETag "3", got %s`, etag)
+	}
+}
+
+func TestPatchKeyValue_RetriesOnConcurrentWrite(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	gomock.InOrder(
+		mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+			Key: "testKey", Value: map[string]interface{}{"a": "b"}, Version: 2,
+		}, nil),
+		mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), uint64(2)).Return(nil, db.ErrVersionMismatch),
+		mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+			Key: "testKey", Value: map[string]interface{}{"a": "x"}, Version: 3,
+		}, nil),
+		mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), uint64(3)).Return(&models.KeyValue{
+			Key: "testKey", Value: map[string]interface{}{"a": "x", "c": "d"}, Version: 4,
+		}, nil),
+	)
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/merge-patch+json", `{"c": "d"}`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_IfMatchMismatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key: "testKey", Value: map[string]interface{}{"a": "b"}, Version: 5,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/merge-patch+json", `{"c": "d"}`)
+	c.Request.Header.Set("If-Match", `"4"`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status 412, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_NotObject(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key: "testKey", Value: map[string]interface{}{"a": "b"}, Version: 1,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/merge-patch+json", `["not", "an", "object"]`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_MergePatchMalformed(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	// Некорректное тело отклоняется до чтения ключа, даже если ключа нет
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "missingKey", "application/merge-patch+json", `{"a": `)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_UnsupportedContentType(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "text/plain", `a=b`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status 415, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_JSONPatch(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"tags": []interface{}{"a", "b"}, "owner": "x"},
+		Version: 2,
+	}, nil)
+	mockStorage.EXPECT().Update(gomock.Any(), gomock.Any(), uint64(2)).DoAndReturn(
+		func(_ context.Context, in *models.KeyValue, _ uint64) (*models.KeyValue, error) {
+			expected := map[string]interface{}{"tags": []interface{}{"b", "c"}, "owner": "y"}
+			if !reflect.DeepEqual(in.Value, expected) {
+				t.Errorf("expected patched value %v, got %v", expected, in.Value)
+			}
+			return &models.KeyValue{Key: in.Key, Value: in.Value, Version: 3}, nil
+		})
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/json-patch+json", `[
+		{"op": "test", "path": "/owner", "value": "x"},
+		{"op": "replace", "path": "/owner", "value": "y"},
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "add", "path": "/tags/-", "value": "c"}
+	]`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_JSONPatchTestFailed(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key: "testKey", Value: map[string]interface{}{"owner": "x"}, Version: 2,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/json-patch+json",
+		`[{"op": "test", "path": "/owner", "value": "z"}, {"op": "replace", "path": "/owner", "value": "y"}]`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_JSONPatchInvalidPath(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "testKey").Return(&models.KeyValue{
+		Key: "testKey", Value: map[string]interface{}{"owner": "x"}, Version: 2,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/json-patch+json", `[{"op": "remove", "path": "/missing/field"}]`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestPatchKeyValue_JSONPatchMalformed(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c := newPatchContext(w, "testKey", "application/json-patch+json", `[{"op": "frobnicate", "path": "/a"}]`)
+
+	h.PatchKeyValue(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestBatchKeyValues_Success(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Batch(gomock.Any(), gomock.Len(2), false).Return([]db.BatchResult{
+		{Item: &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}, Version: 1}},
+		{Err: db.ErrNotFound},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv/_batch", bytes.NewBufferString(
+		`{"operations": [{"op": "create", "key": "a", "value": {"n": 1}}, {"op": "get", "key": "b"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.BatchKeyValues(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Result []models.BatchItemResult `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(response.Result) != 2 || response.Result[0].Status != http.StatusOK || response.Result[1].Status != http.StatusNotFound {
+		t.Errorf("unexpected results %+v", response.Result)
+	}
+}
+
+func TestBatchKeyValues_AtomicRolledBack(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Batch(gomock.Any(), gomock.Any(), true).Return([]db.BatchResult{
+		{Err: db.ErrBatchAborted},
+		{Err: db.ErrVersionMismatch},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv/_batch", bytes.NewBufferString(
+		`{"atomic": true, "operations": [{"op": "delete", "key": "a"}, {"op": "update", "key": "b", "value": {"n": 2}, "if_version": 3}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.BatchKeyValues(c)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
+	}
+
+	var response struct {
+		Result []models.BatchItemResult `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Result[0].Status != http.StatusFailedDependency || response.Result[1].Status != http.StatusPreconditionFailed {
+		t.Errorf("unexpected results %+v", response.Result)
+	}
+}
+
+func TestBatchKeyValues_InvalidOperation(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv/_batch", bytes.NewBufferString(
+		`{"operations": [{"op": "get", "key": "a"}, {"op": "create", "key": "b"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.BatchKeyValues(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte("Operation 1")) {
+		t.Errorf("expected error to name the operation, got %s", w.Body.String())
+	}
+}
+
+func TestDeleteKeyValue_ReturnsDeletedVersion(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Delete(gomock.Any(), "testKey", uint64(0)).Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"data": "removed"},
+		Version: 7,
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodDelete, "/testKey", nil)
+
+	h.DeleteKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"7"` {
+		t.Errorf(`expected ETag "7", got %s`, etag)
+	}
+
+	var response struct {
+		Deleted models.KeyValue `json:"deleted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Deleted.Version != 7 || response.Deleted.Value["data"] != "removed" {
+		t.Errorf("unexpected deleted item %+v", response.Deleted)
+	}
+}
+
+func TestUpdateKeyValue_UpsertCreates(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutUpsert, uint64(0)).Return(&models.KeyValue{
+		Key:     "testKey",
+		Value:   map[string]interface{}{"data": "value"},
+		Version: 1,
+	}, true, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/testKey?mode=upsert", bytes.NewBufferString(`{"value": {"data": "value"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.UpdateKeyValue(c)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+}
+
+func TestUpdateKeyValue_InvalidMode(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/testKey?mode=merge", bytes.NewBufferString(`{"value": {"data": "value"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.UpdateKeyValue(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestWatchKeyValues_StreamsEvents(t *testing.T) {
+	_, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	b := watch.NewBroadcaster(watch.DefaultBuffer)
+	h := handlers.NewHandler(mockStorage, handlers.WithBroadcaster(b))
+
+	r := gin.New()
+	r.GET("/kv/_watch", h.WatchKeyValues)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// Заголовки отправляются после подписки, поэтому событие не потеряется
+	resp, err := http.Get(srv.URL + "/kv/_watch?prefix=app/")
+	if err != nil {
+		t.Fatalf("watch request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b.Publish(models.Event{Type: models.EventUpdate, Key: "other", Version: 1})
+	b.Publish(models.Event{Type: models.EventUpdate, Key: "app/config", Version: 2})
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "data:") {
+		lines = append(lines, scanner.Text())
+	}
+	data := scanner.Text()
+
+	if len(lines) == 0 || lines[len(lines)-1] != "event:update" {
+		t.Errorf("expected update event, got %q", lines)
+	}
+	if !strings.Contains(data, `"key":"app/config"`) {
+		t.Errorf("expected event for app/config, got %q", data)
+	}
+}
+
+func TestWatchKeyValues_EndsOnShutdown(t *testing.T) {
+	_, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	b := watch.NewBroadcaster(watch.DefaultBuffer)
+	h := handlers.NewHandler(mockStorage, handlers.WithBroadcaster(b))
+
+	r := gin.New()
+	r.GET("/kv/_watch", h.WatchKeyValues)
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.RegisterOnShutdown(b.Shutdown)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/kv/_watch")
+	if err != nil {
+		t.Fatalf("watch request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- srv.Config.Shutdown(ctx)
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	if !strings.Contains(string(body), "Server is shutting down") {
+		t.Errorf("expected shutdown error event, got %q", body)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("expected graceful shutdown, got %v", err)
+	}
+}
+
+func TestListChanges_Success(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Changes(gomock.Any(), db.ChangesOptions{Since: 5, Limit: 2}).Return([]*models.Change{
+		{Revision: 6, Event: models.Event{Type: models.EventCreate, Key: "a"}},
+		{Revision: 8, Event: models.Event{Type: models.EventDelete, Key: "a"}},
+	}, uint64(8), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv/_changes?since=5&limit=2", nil)
+
+	h.ListChanges(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Revision != 8 {
+		t.Errorf("expected revision 8, got %d", response.Revision)
+	}
+}
+
+func TestListChanges_Compacted(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Changes(gomock.Any(), gomock.Any()).Return(nil, uint64(0), fmt.Errorf("failed to read changes: %w", db.ErrRevisionCompacted))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv/_changes?since=1", nil)
+
+	h.ListChanges(c)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("expected status 410, got %d", w.Code)
+	}
+}
+
+func TestListChanges_FromHead(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Changes(gomock.Any(), db.ChangesOptions{FromHead: true}).Return(nil, uint64(42), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv/_changes?since=now", nil)
+
+	h.ListChanges(c)
+
+	var response models.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || response.Revision != 42 {
+		t.Errorf("expected status 200 with revision 42, got %d with %d", w.Code, response.Revision)
+	}
+}
+
+func TestGetKeyValue_Version(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().GetVersion(gomock.Any(), "testKey", uint64(2)).Return(nil, fmt.Errorf("wrapped: %w", db.ErrVersionNotFound))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/kv/testKey?version=2", nil)
+
+	h.GetKeyValue(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestRestoreKeyValue_Success(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	old := &models.KeyValue{Key: "testKey", Value: map[string]interface{}{"data": "old"}, Version: 2}
+	gomock.InOrder(
+		mockStorage.EXPECT().GetVersion(gomock.Any(), "testKey", uint64(2)).Return(old, nil),
+		mockStorage.EXPECT().Put(gomock.Any(), gomock.Any(), db.PutUpsert, uint64(5)).DoAndReturn(
+			func(_ context.Context, in *models.KeyValue, _ db.PutMode, _ uint64) (*models.KeyValue, bool, error) {
+				if !reflect.DeepEqual(in.Value, old.Value) {
+					t.Errorf("expected old value to be written, got %v", in.Value)
+				}
+				return &models.KeyValue{Key: "testKey", Value: in.Value, Version: 6}, false, nil
+			}),
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv/testKey/restore?version=2", nil)
+	c.Request.Header.Set("If-Match", `"5"`)
+
+	h.RestoreKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"6"` {
+		t.Errorf(`expected ETag "6", got %s`, etag)
+	}
+}
+
+func TestRestoreTrashKeyValue_Conflict(t *testing.T) {
+	h, mockStorage, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().RestoreTrash(gomock.Any(), "testKey").Return(nil, fmt.Errorf("failed to restore key: %w", db.ErrAlreadyExists))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "testKey"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/kv/_trash/testKey/restore", nil)
+
+	h.RestoreTrashKeyValue(c)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+}
+
+func TestResolveNamespace_HasDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	namespaces := db.NewMockNamespaces(ctrl)
+	h := handlers.NewHandler(db.NewMockStorage(ctrl), handlers.WithNamespaces(namespaces))
+
+	namespaces.EXPECT().Namespace(gomock.Any(), "team-a").DoAndReturn(
+		func(ctx context.Context, name string) (db.Storage, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected namespace lookup to have a deadline")
+			}
+			return nil, context.DeadlineExceeded
+		})
+
+	r := gin.New()
+	r.GET("/ns/:ns/kv/:id", h.ResolveNamespace, h.GetKeyValue)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ns/team-a/kv/k", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504, got %d", w.Code)
+	}
+}
+
+func TestNamespaceRoutes_ResolveNamespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	defaultStorage := db.NewMockStorage(ctrl)
+	teamStorage := db.NewMockStorage(ctrl)
+	namespaces := db.NewMockNamespaces(ctrl)
+	h := handlers.NewHandler(defaultStorage, handlers.WithNamespaces(namespaces))
+
+	namespaces.EXPECT().Namespace(gomock.Any(), "team-a").Return(teamStorage, nil)
+	namespaces.EXPECT().Namespace(gomock.Any(), "missing").Return(nil, db.ErrNamespaceNotFound)
+	teamStorage.EXPECT().Get(gomock.Any(), "k").Return(&models.KeyValue{Key: "k", Version: 1}, nil)
+	defaultStorage.EXPECT().Get(gomock.Any(), "k").Return(&models.KeyValue{Key: "k", Version: 2}, nil)
+
+	r := gin.New()
+	r.GET("/ns/:ns/kv/:id", h.ResolveNamespace, h.GetKeyValue)
+
+	for path, status := range map[string]int{
+		"/ns/team-a/kv/k":  http.StatusOK,
+		"/ns/default/kv/k": http.StatusOK,
+		"/ns/missing/kv/k": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", path, status, w.Code)
+		}
+	}
+}
+
+func TestCreateNamespace_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	namespaces := db.NewMockNamespaces(ctrl)
+	h := handlers.NewHandler(db.NewMockStorage(ctrl), handlers.WithNamespaces(namespaces))
+	namespaces.EXPECT().CreateNamespace(gomock.Any(), "team-a").Return(nil, db.ErrNamespaceExists)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/ns", strings.NewReader(`{"name": "team-a"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateNamespace(c)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", w.Code)
+	}
+}
+
+func TestDeleteNamespace_Default(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	namespaces := db.NewMockNamespaces(ctrl)
+	h := handlers.NewHandler(db.NewMockStorage(ctrl), handlers.WithNamespaces(namespaces))
+	namespaces.EXPECT().DeleteNamespace(gomock.Any(), db.DefaultNamespace).Return(db.ErrDefaultNamespace)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/ns/default", nil)
+	c.Params = gin.Params{{Key: "ns", Value: db.DefaultNamespace}}
+
+	h.DeleteNamespace(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func setupPolicyTest(t *testing.T) (*handlers.Handler, *db.MockStorage, *gomock.Controller) {
+	ctrl := gomock.NewController(t)
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	policy, err := auth.NewPolicy(auth.PolicyFile{
+		Roles: map[string][]auth.Rule{
+			"payments-writer": {{Verbs: []auth.Verb{auth.VerbGet, auth.VerbCreate, auth.VerbUpdate}, Keys: []string{"payments/*"}}},
+		},
+		Principals: map[string][]string{"billing": {"payments-writer"}},
+	})
+	if err != nil {
+		t.Fatalf("new policy: %v", err)
+	}
+
+	mockStorage := db.NewMockStorage(ctrl)
+	return handlers.NewHandler(mockStorage, handlers.WithPolicy(policy)), mockStorage, ctrl
+}
+
+func withPrincipal(r *http.Request, name string) *http.Request {
+	return r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Name: name}))
+}
+
+func TestDeleteKeyValue_Forbidden(t *testing.T) {
+	h, _, ctrl := setupPolicyTest(t)
+	defer ctrl.Finish()
+
+	// Хранилище не должно вызываться: mock без ожиданий упадет на любом вызове
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "payments/1"}}
+	c.Request = withPrincipal(httptest.NewRequest(http.MethodDelete, "/kv/payments/1", nil), "billing")
+
+	h.DeleteKeyValue(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "billing is not allowed to delete") {
+		t.Errorf("expected denial reason, got %s", w.Body.String())
+	}
+}
+
+func TestGetKeyValue_AllowedByPolicy(t *testing.T) {
+	h, mockStorage, ctrl := setupPolicyTest(t)
+	defer ctrl.Finish()
+
+	mockStorage.EXPECT().Get(gomock.Any(), "payments/a/b").Return(&models.KeyValue{Key: "payments/a/b", Version: 1}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "payments/a/b"}}
+	c.Request = withPrincipal(httptest.NewRequest(http.MethodGet, "/kv/payments/a/b", nil), "billing")
+
+	h.GetKeyValue(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestBatchKeyValues_Forbidden(t *testing.T) {
+	h, _, ctrl := setupPolicyTest(t)
+	defer ctrl.Finish()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = withPrincipal(httptest.NewRequest(http.MethodPost, "/kv/_batch", bytes.NewBufferString(
+		`{"operations": [{"op": "get", "key": "payments/1"}, {"op": "delete", "key": "orders/1"}]}`)), "billing")
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.BatchKeyValues(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "operation 1") {
+		t.Errorf("expected error to name the operation, got %s", w.Body.String())
+	}
+}
+
+func TestSetLogLevel_Package(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+	t.Cleanup(func() { logger.ResetPackageLevel("db") })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(`{"package": "db", "level": "debug"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.SetLogLevel(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Result models.LogLevels `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if response.Result.Level != "info" || response.Result.Packages["db"] != "debug" {
+		t.Errorf("unexpected levels %+v", response.Result)
+	}
+}
+
+func TestGetLogLevel_NamespaceAdminForbidden(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	// Шаблон "?" совпадает с любым однобуквенным именем, но право над
+	// сервером дает только правило без списка пространств имен
+	principal := &auth.Principal{Name: "team", Rules: []auth.Rule{
+		{Verbs: []auth.Verb{auth.VerbAdmin}, Keys: []string{"*"}, Namespaces: []string{"?"}},
+	}}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	r := httptest.NewRequest(http.MethodGet, "/admin/loglevel", nil)
+	c.Request = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+
+	h.GetLogLevel(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", w.Code)
+	}
+}
+
+func TestSetLogLevel_Invalid(t *testing.T) {
+	h, _, ctrl := setupTest(t)
+	defer ctrl.Finish()
+
+	for _, body := range []string{
+		`{"level": "verbose"}`,
+		`{"package": "unknown", "level": "debug"}`,
+		`{}`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/admin/loglevel", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.SetLogLevel(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetKeyValueHistory возвращает сохраненные версии ключа от новой к старой
+func (h *Handler) GetKeyValueHistory(c *gin.Context) {
+	key := c.Param("id")
+
+	if !h.authorize(c, key, auth.VerbGet) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Get)
+	defer cancel()
+
+	items, err := h.storageFor(c).History(ctx, key)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error getting key history", err, logrus.Fields{"key": key})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Fetched key history successfully", logrus.Fields{"key": key, "count": len(items)})
+	c.JSON(http.StatusOK, models.Response{
+		Result:  items,
+		Message: "Key history getted successfully",
+	})
+}
+
+// RestoreKeyValue записывает версию из параметра version как новую версию ключа.
+// С заголовком If-Match запись выполняется, только если текущая версия не изменилась.
+func (h *Handler) RestoreKeyValue(c *gin.Context) {
+	key := c.Param("id")
+
+	version, ok := parseVersion(c, c.Query("version"))
+	if !ok {
+		return
+	}
+
+	ifVersion, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	// Восстановление читает старую версию и может создать удаленный ключ заново
+	if !h.authorize(c, key, auth.VerbGet, auth.VerbCreate, auth.VerbUpdate) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Update)
+	defer cancel()
+
+	restored, err := db.Restore(ctx, h.storageFor(c), key, version, ifVersion)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error restoring key version", err, logrus.Fields{"key": key, "version": version})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Restored key version successfully", logrus.Fields{"key": key, "version": version})
+	c.Header("ETag", etag(restored.Version))
+	c.JSON(http.StatusOK, models.Response{
+		Result:  restored,
+		Message: "Key version restored successfully",
+	})
+}
+
+// parseVersion разбирает номер версии из параметра запроса.
+// При ошибке клиенту уже отправлен ответ 400.
+func parseVersion(c *gin.Context, raw string) (uint64, bool) {
+	version, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || version == 0 {
+		log.LogInfo(c.Request.Context(), "Invalid version", logrus.Fields{"version": raw})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "version must be a positive integer",
+		})
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevelRequest - тело PUT /admin/loglevel. Без Package меняется общий
+// уровень, с Package - уровень пакета, а пустой Level возвращает пакету
+// общий уровень.
+type LogLevelRequest struct {
+	Level   string `json:"level"`
+	Package string `json:"package"`
+}
+
+// GetLogLevel возвращает текущие уровни логов
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	// Уровень логов касается всего сервера, а не отдельных пространств имен
+	if !h.authorizeServer(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Result: currentLogLevels(),
+	})
+}
+
+// SetLogLevel меняет общий уровень логов или уровень пакета без перезапуска
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	var request LogLevelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.LogInfo(c.Request.Context(), "Invalid log level request", logrus.Fields{"error": err})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid body",
+		})
+		return
+	}
+
+	if !h.authorizeServer(c) {
+		return
+	}
+
+	if msg := applyLogLevel(request); msg != "" {
+		log.LogInfo(c.Request.Context(), msg, logrus.Fields{"log_level": request.Level, "package": request.Package})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: msg,
+		})
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Log level changed", logrus.Fields{"log_level": request.Level, "package": request.Package})
+	c.JSON(http.StatusOK, models.Response{
+		Result:  currentLogLevels(),
+		Message: "Log level changed",
+	})
+}
+
+// applyLogLevel применяет запрос и возвращает текст ошибки для клиента
+func applyLogLevel(request LogLevelRequest) string {
+	if request.Package != "" && !knownLogPackage(request.Package) {
+		return fmt.Sprintf("Unknown package %q, expected one of: %s", request.Package, strings.Join(logger.PackageNames(), ", "))
+	}
+
+	if request.Level == "" {
+		if request.Package == "" {
+			return "Level is required"
+		}
+		logger.ResetPackageLevel(request.Package)
+		return ""
+	}
+
+	level, err := logrus.ParseLevel(request.Level)
+	if err != nil {
+		return fmt.Sprintf("Invalid level %q", request.Level)
+	}
+	if request.Package == "" {
+		logger.SetLevel(level)
+	} else {
+		logger.SetPackageLevel(request.Package, level)
+	}
+	return ""
+}
+
+func knownLogPackage(name string) bool {
+	for _, known := range logger.PackageNames() {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
+
+func currentLogLevels() models.LogLevels {
+	levels := models.LogLevels{
+		Level:    logger.Level().String(),
+		Packages: map[string]string{},
+	}
+	for name, level := range logger.PackageLevels() {
+		levels.Packages[name] = level.String()
+	}
+	return levels
+}
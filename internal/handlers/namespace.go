@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// namespaceStorageKey - ключ контекста gin, под которым ResolveNamespace
+// сохраняет хранилище пространства имен запроса
+const namespaceStorageKey = "kv.namespace.storage"
+
+// Broadcasters возвращает рассылку изменений пространства имен, см. watch.Namespaces
+type Broadcasters interface {
+	Broadcaster(namespace string) *watch.Broadcaster
+}
+
+// CreateNamespaceRequest - тело запроса создания пространства имен
+type CreateNamespaceRequest struct {
+	Name string `json:"name"`
+}
+
+// ResolveNamespace - middleware маршрутов /ns/:ns: находит хранилище
+// пространства имен, с которым затем работают обработчики ключей.
+// Пространство default обслуживается тем же хранилищем, что и /kv.
+func (h *Handler) ResolveNamespace(c *gin.Context) {
+	name := c.Param("ns")
+	if name == db.DefaultNamespace {
+		c.Next()
+		return
+	}
+	if h.namespaces == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, models.Response{
+			Error: "Namespace not found",
+		})
+		return
+	}
+
+	// Поиск пространства - такое же обращение к хранилищу, как чтение ключа
+	ctx, cancel := storageContext(c, h.timeouts.Get)
+	storage, err := h.namespaces.Namespace(ctx, name)
+	cancel()
+	if err != nil {
+		log.LogInfo(c.Request.Context(), "Failed to resolve namespace", logrus.Fields{"namespace": name, "error": err})
+		respondStorageError(c, err)
+		c.Abort()
+		return
+	}
+	c.Set(namespaceStorageKey, storage)
+	c.Next()
+}
+
+// storageFor возвращает хранилище пространства имен запроса
+func (h *Handler) storageFor(c *gin.Context) db.Storage {
+	if storage, ok := c.Get(namespaceStorageKey); ok {
+		return storage.(db.Storage)
+	}
+	return h.storage
+}
+
+// broadcasterFor возвращает рассылку изменений пространства имен запроса
+// или nil, если подписка в нем не включена
+func (h *Handler) broadcasterFor(c *gin.Context) *watch.Broadcaster {
+	name := c.Param("ns")
+	if name == "" || name == db.DefaultNamespace {
+		return h.broadcaster
+	}
+	if h.broadcasters == nil {
+		return nil
+	}
+	return h.broadcasters.Broadcaster(name)
+}
+
+// CreateNamespace создает пространство имен
+func (h *Handler) CreateNamespace(c *gin.Context) {
+	if !h.namespacesEnabled(c) {
+		return
+	}
+
+	var request CreateNamespaceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.LogInfo(c.Request.Context(), "Invalid namespace request", logrus.Fields{"error": err})
+		c.JSON(http.StatusBadRequest, models.Response{
+			Error: "Invalid body",
+		})
+		return
+	}
+
+	if !h.authorizeNamespace(c, request.Name) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Create)
+	defer cancel()
+
+	namespace, err := h.namespaces.CreateNamespace(ctx, request.Name)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error creating namespace", err, logrus.Fields{"namespace": request.Name})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Created namespace successfully", logrus.Fields{"namespace": namespace.Name})
+	c.JSON(http.StatusCreated, models.Response{
+		Result:  namespace,
+		Message: "Namespace created successfully",
+	})
+}
+
+// ListNamespaces возвращает все пространства имен
+func (h *Handler) ListNamespaces(c *gin.Context) {
+	if !h.namespacesEnabled(c) {
+		return
+	}
+
+	// "*" - все пространства: правило, ограниченное пространствами, не подходит
+	if !h.authorizeNamespace(c, "*") {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.List)
+	defer cancel()
+
+	namespaces, err := h.namespaces.ListNamespaces(ctx)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error listing namespaces", err, nil)
+		respondStorageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Result:  namespaces,
+		Message: "Namespaces listed successfully",
+	})
+}
+
+// DeleteNamespace удаляет пространство имен вместе со всеми его ключами
+func (h *Handler) DeleteNamespace(c *gin.Context) {
+	if !h.namespacesEnabled(c) {
+		return
+	}
+	name := c.Param("ns")
+
+	if !h.authorizeNamespace(c, name) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Delete)
+	defer cancel()
+
+	if err := h.namespaces.DeleteNamespace(ctx, name); err != nil {
+		log.LogError(c.Request.Context(), "Error deleting namespace", err, logrus.Fields{"namespace": name})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Deleted namespace successfully", logrus.Fields{"namespace": name})
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Namespace deleted successfully",
+	})
+}
+
+func (h *Handler) namespacesEnabled(c *gin.Context) bool {
+	if h.namespaces == nil {
+		c.JSON(http.StatusNotImplemented, models.Response{
+			Error: "Namespaces are not enabled",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListTrash возвращает страницу мягко удаленных ключей с параметрами как у ListKeyValues
+func (h *Handler) ListTrash(c *gin.Context) {
+	opts, ok := listOptions(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorizePrefix(c, opts.Prefix, auth.VerbGet) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.List)
+	defer cancel()
+
+	items, nextCursor, err := h.storageFor(c).ListTrash(ctx, opts)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error listing trash", err, logrus.Fields{"prefix": opts.Prefix})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Listed trash successfully", logrus.Fields{"prefix": opts.Prefix, "count": len(items)})
+	c.JSON(http.StatusOK, models.Response{
+		Result:     items,
+		NextCursor: nextCursor,
+		Message:    "Trash listed successfully",
+	})
+}
+
+// RestoreTrashKeyValue возвращает ключ из корзины.
+// Если ключ с тем же именем уже создан заново, возвращается 409.
+func (h *Handler) RestoreTrashKeyValue(c *gin.Context) {
+	key := c.Param("id")
+
+	if !h.authorize(c, key, auth.VerbCreate) {
+		return
+	}
+
+	ctx, cancel := storageContext(c, h.timeouts.Create)
+	defer cancel()
+
+	restored, err := h.storageFor(c).RestoreTrash(ctx, key)
+	if err != nil {
+		log.LogError(c.Request.Context(), "Error restoring key from trash", err, logrus.Fields{"key": key})
+		respondStorageError(c, err)
+		return
+	}
+
+	log.LogInfo(c.Request.Context(), "Restored key from trash successfully", logrus.Fields{"key": key})
+	c.Header("ETag", etag(restored.Version))
+	c.JSON(http.StatusOK, models.Response{
+		Result:  restored,
+		Message: "Key restored successfully",
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/auth"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// watchKeepAlive - интервал комментариев, которые не дают прокси
+// закрыть соединение без событий
+const watchKeepAlive = 15 * time.Second
+
+// WatchKeyValues передает изменения ключей с префиксом prefix как Server-Sent Events.
+// Имя события совпадает с типом изменения: create, update или delete.
+// Если клиент не успевает читать события, поток завершается событием error.
+func (h *Handler) WatchKeyValues(c *gin.Context) {
+	broadcaster := h.broadcasterFor(c)
+	if broadcaster == nil {
+		c.JSON(http.StatusNotImplemented, models.Response{
+			Error: "Watch is not enabled",
+		})
+		return
+	}
+
+	prefix := c.Query("prefix")
+	if !h.authorizePrefix(c, prefix, auth.VerbGet) {
+		return
+	}
+
+	sub := broadcaster.Subscribe(prefix)
+	defer sub.Close()
+
+	log.LogInfo(c.Request.Context(), "Watch started", logrus.Fields{"prefix": prefix})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("Content-Type", "text/event-stream")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(watchKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				log.LogInfo(c.Request.Context(), "Watch subscriber dropped", logrus.Fields{"prefix": prefix, "error": sub.Err()})
+				message := "Subscriber is too slow, reconnect"
+				switch {
+				case errors.Is(sub.Err(), watch.ErrClosed):
+					message = "Namespace deleted"
+				case errors.Is(sub.Err(), watch.ErrShutdown):
+					message = "Server is shutting down"
+				}
+				c.SSEvent("error", models.Response{Error: message})
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			log.LogInfo(c.Request.Context(), "Watch closed by client", logrus.Fields{"prefix": prefix})
+			return false
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// levels хранит общий уровень и уровни, переопределенные для пакетов.
+// Уровень logrus.Logger равен самому подробному из них, а записи
+// отбрасываются по уровню своего пакета.
+var levels = struct {
+	sync.RWMutex
+	base     logrus.Level
+	saved    logrus.Level
+	packages map[string]logrus.Level
+}{base: logrus.InfoLevel, packages: map[string]logrus.Level{}}
+
+// limits - неизменяемый снимок levels, по которому enabled отбрасывает
+// записи. Снимок заменяется целиком при каждом изменении уровней, поэтому
+// запись лога не берет блокировку.
+type limits struct {
+	base     logrus.Level
+	packages map[string]logrus.Level
+}
+
+var current atomic.Pointer[limits]
+
+func init() {
+	current.Store(&limits{base: logrus.InfoLevel})
+}
+
+// Level возвращает общий уровень логов
+func Level() logrus.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	return levels.base
+}
+
+// SetLevel меняет общий уровень логов. Переопределения пакетов сохраняются.
+func SetLevel(level logrus.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base = level
+	applyLevels()
+}
+
+// PackageLevels возвращает переопределенные уровни пакетов
+func PackageLevels() map[string]logrus.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	out := make(map[string]logrus.Level, len(levels.packages))
+	for name, level := range levels.packages {
+		out[name] = level
+	}
+	return out
+}
+
+// SetPackageLevel переопределяет уровень логов пакета name
+func SetPackageLevel(name string, level logrus.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.packages[name] = level
+	applyLevels()
+}
+
+// ResetPackageLevel возвращает пакету name общий уровень
+func ResetPackageLevel(name string) {
+	levels.Lock()
+	defer levels.Unlock()
+	delete(levels.packages, name)
+	applyLevels()
+}
+
+// ToggleDebug переключает общий уровень на debug, а при повторном
+// вызове возвращает уровень, который был до переключения. Если сервер
+// запущен с уровнем debug, уровень переключается между debug и info.
+func ToggleDebug() logrus.Level {
+	levels.Lock()
+	defer levels.Unlock()
+	if levels.base == logrus.DebugLevel {
+		if levels.saved == logrus.DebugLevel {
+			levels.saved = logrus.InfoLevel
+		}
+		levels.base = levels.saved
+	} else {
+		levels.saved, levels.base = levels.base, logrus.DebugLevel
+	}
+	applyLevels()
+	return levels.base
+}
+
+// ToggleDebugOnSignal вызывает ToggleDebug при каждом получении sig,
+// например SIGUSR1, пока не отменен ctx
+func ToggleDebugOnSignal(ctx context.Context, sig os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				level := ToggleDebug()
+				LogInfo("Log level toggled", logrus.Fields{"log_level": level.String(), "signal": sig.String()})
+			}
+		}
+	}()
+}
+
+// resetLevels задает общий уровень и убирает переопределения пакетов
+func resetLevels(level logrus.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base, levels.saved = level, level
+	levels.packages = map[string]logrus.Level{}
+	applyLevels()
+}
+
+// applyLevels публикует снимок уровней для enabled и выставляет
+// logrus.Logger самый подробный из уровней, чтобы записи пакетов
+// с более подробным уровнем не отбрасывались им.
+// Вызывается под levels.Lock.
+func applyLevels() {
+	snapshot := &limits{base: levels.base, packages: make(map[string]logrus.Level, len(levels.packages))}
+	for name, level := range levels.packages {
+		snapshot.packages[name] = level
+	}
+	current.Store(snapshot)
+
+	if Logger == nil {
+		return
+	}
+	max := levels.base
+	for _, level := range levels.packages {
+		if level > max {
+			max = level
+		}
+	}
+	Logger.SetLevel(max)
+}
+
+// enabled сообщает, пишутся ли записи уровня level пакета name.
+// Пустое имя означает общий уровень.
+func enabled(name string, level logrus.Level) bool {
+	snapshot := current.Load()
+	limit, ok := snapshot.packages[name]
+	if !ok {
+		limit = snapshot.base
+	}
+	return level <= limit
+}
+
+// PackageLogger пишет логи через логгер контекста с уровнем своего пакета,
+// см. SetPackageLevel
+type PackageLogger struct {
+	name string
+}
+
+var (
+	packagesMu   sync.Mutex
+	packageNames = map[string]struct{}{}
+)
+
+// Package возвращает логгер пакета name, например "db" или "handlers"
+func Package(name string) *PackageLogger {
+	packagesMu.Lock()
+	packageNames[name] = struct{}{}
+	packagesMu.Unlock()
+	return &PackageLogger{name: name}
+}
+
+// PackageNames возвращает имена пакетов, уровень которых можно переопределить
+func PackageNames() []string {
+	packagesMu.Lock()
+	defer packagesMu.Unlock()
+	names := make([]string, 0, len(packageNames))
+	for name := range packageNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *PackageLogger) LogDebug(ctx context.Context, message string, fields logrus.Fields) {
+	p.log(ctx, logrus.DebugLevel, message, nil, fields)
+}
+
+func (p *PackageLogger) LogInfo(ctx context.Context, message string, fields logrus.Fields) {
+	p.log(ctx, logrus.InfoLevel, message, nil, fields)
+}
+
+func (p *PackageLogger) LogError(ctx context.Context, message string, err error, fields logrus.Fields) {
+	p.log(ctx, logrus.ErrorLevel, message, err, fields)
+}
+
+func (p *PackageLogger) log(ctx context.Context, level logrus.Level, message string, err error, fields logrus.Fields) {
+	if !enabled(p.name, level) {
+		return
+	}
+	entry := FromContext(ctx).WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Log(level, message)
+}
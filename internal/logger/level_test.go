@@ -0,0 +1,73 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPackageLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger.Init(logger.WithFormat(logger.FormatJSON), logger.WithOutput(&buf))
+	ctx := context.Background()
+	db := logger.Package("db")
+	handlers := logger.Package("handlers")
+
+	db.LogDebug(ctx, "db debug", nil)
+	if buf.Len() != 0 {
+		t.Fatalf("debug must be filtered at info level, got %s", buf.String())
+	}
+
+	logger.SetPackageLevel("db", logrus.DebugLevel)
+	db.LogDebug(ctx, "db debug", nil)
+	handlers.LogDebug(ctx, "handlers debug", nil)
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "db debug" {
+		t.Fatalf("expected only the db debug line, got %v", lines)
+	}
+
+	logger.SetPackageLevel("handlers", logrus.ErrorLevel)
+	handlers.LogInfo(ctx, "handlers info", nil)
+	logger.LogInfo("global info", nil)
+	lines = decodeLines(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "global info" {
+		t.Fatalf("expected only the global info line, got %v", lines)
+	}
+
+	logger.ResetPackageLevel("db")
+	db.LogDebug(ctx, "db debug", nil)
+	if buf.Len() != 0 {
+		t.Fatalf("debug must be filtered after reset, got %s", buf.String())
+	}
+	if levels := logger.PackageLevels(); len(levels) != 1 || levels["handlers"] != logrus.ErrorLevel {
+		t.Errorf("unexpected package levels %v", levels)
+	}
+}
+
+func TestToggleDebug(t *testing.T) {
+	logger.Init(logger.WithLevel(logrus.WarnLevel))
+
+	if level := logger.ToggleDebug(); level != logrus.DebugLevel {
+		t.Fatalf("expected debug after first toggle, got %s", level)
+	}
+	if level := logger.ToggleDebug(); level != logrus.WarnLevel {
+		t.Fatalf("expected warn to be restored, got %s", level)
+	}
+}
+
+func TestToggleDebug_StartedAtDebug(t *testing.T) {
+	logger.Init(logger.WithLevel(logrus.DebugLevel))
+
+	if level := logger.ToggleDebug(); level != logrus.InfoLevel {
+		t.Fatalf("expected info after first toggle, got %s", level)
+	}
+	if level := logger.ToggleDebug(); level != logrus.DebugLevel {
+		t.Fatalf("expected debug after second toggle, got %s", level)
+	}
+	if level := logger.ToggleDebug(); level != logrus.InfoLevel {
+		t.Fatalf("expected info after third toggle, got %s", level)
+	}
+}
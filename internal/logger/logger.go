@@ -1,12 +1,55 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/sirupsen/logrus"
 )
 
 var Logger *logrus.Logger
 
-func Init() {
+// Форматы вывода логов
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Option настраивает логгер в Init
+type Option func(*logrus.Logger)
+
+// WithFormat задает формат вывода: FormatText или FormatJSON
+func WithFormat(format string) Option {
+	return func(l *logrus.Logger) {
+		if format == FormatJSON {
+			l.SetFormatter(&logrus.JSONFormatter{})
+			return
+		}
+		l.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+	}
+}
+
+// WithLevel задает общий минимальный уровень записей, см. SetLevel
+func WithLevel(level logrus.Level) Option {
+	return func(l *logrus.Logger) {
+		l.SetLevel(level)
+	}
+}
+
+// WithOutput задает, куда пишутся логи
+func WithOutput(w io.Writer) Option {
+	return func(l *logrus.Logger) {
+		l.SetOutput(w)
+	}
+}
+
+// Init создает глобальный логгер. По умолчанию это текст уровня info в stderr.
+// Переопределенные уровни пакетов сбрасываются.
+func Init(opts ...Option) {
 	Logger = logrus.New()
 
 	// Устанавливаем формат вывода
@@ -15,12 +58,67 @@ func Init() {
 	})
 
 	Logger.SetLevel(logrus.InfoLevel)
+
+	for _, opt := range opts {
+		opt(Logger)
+	}
+	resetLevels(Logger.GetLevel())
+}
+
+// ParseFormat проверяет название формата из конфигурации
+func ParseFormat(format string) (string, error) {
+	switch format {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// OpenOutput возвращает вывод по названию: "stdout", "stderr" или путь файла,
+// в который записи дописываются. Пустое название означает stderr.
+func OpenOutput(name string) (io.Writer, error) {
+	switch name {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	default:
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		return f, nil
+	}
 }
 
 func LogInfo(message string, fields logrus.Fields) {
-	Logger.WithFields(fields).Info(message)
+	if enabled("", logrus.InfoLevel) {
+		Logger.WithFields(fields).Info(message)
+	}
 }
 
 func LogError(message string, err error, fields logrus.Fields) {
-	Logger.WithFields(fields).WithError(err).Error(message)
+	if enabled("", logrus.ErrorLevel) {
+		Logger.WithFields(fields).WithError(err).Error(message)
+	}
+}
+
+type entryKey struct{}
+
+// WithFields возвращает контекст, логгер которого добавляет fields
+// к каждой записи, например идентификатор запроса
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return context.WithValue(ctx, entryKey{}, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext возвращает логгер контекста или глобальный логгер,
+// если в контексте его нет
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(Logger)
 }
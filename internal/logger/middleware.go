@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// requestLog пишет итоговые строки запросов. Уровень пакета "http"
+// позволяет скрыть их, не меняя уровень остальных логов.
+var requestLog = Package("http")
+
+// maxRequestIDLength ограничивает длину идентификатора от клиента,
+// чтобы он не раздувал каждую запись лога
+const maxRequestIDLength = 128
+
+// Middleware берет идентификатор запроса из X-Request-ID или создает новый,
+// возвращает его в ответе и кладет в контекст запроса логгер с полем
+// request_id. После обработки пишется строка лога с итогом запроса.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithFields(c.Request.Context(), logrus.Fields{"request_id": id}))
+
+		c.Next()
+
+		// Следующие обработчики могли дополнить логгер, например именем клиента
+		requestLog.LogInfo(c.Request.Context(), "Request handled", logrus.Fields{
+			"method":  c.Request.Method,
+			"path":    c.Request.URL.Path,
+			"status":  c.Writer.Status(),
+			"latency": time.Since(start).String(),
+			"client":  c.ClientIP(),
+		})
+	}
+}
+
+// validRequestID принимает только короткие идентификаторы из печатных
+// символов без пробелов, чтобы клиент не мог подделать строки лога
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,97 @@
+package logger_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line is not JSON: %s", scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger.Init(logger.WithFormat(logger.FormatJSON), logger.WithOutput(&buf))
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(logger.Middleware())
+	r.GET("/kv/:id", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).WithFields(logrus.Fields{"key": c.Param("id")}).Info("Key requested")
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "propagated", incoming: "req-42", keep: true},
+		{name: "generated"},
+		{name: "invalid replaced", incoming: "bad id\nforged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/kv/a", nil)
+			if tt.incoming != "" {
+				req.Header.Set(logger.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(logger.RequestIDHeader)
+			if id == "" {
+				t.Fatal("expected request ID in response")
+			}
+			if tt.keep && id != tt.incoming {
+				t.Errorf("expected request ID %q, got %q", tt.incoming, id)
+			}
+			if !tt.keep && id == tt.incoming {
+				t.Errorf("expected a new request ID instead of %q", tt.incoming)
+			}
+
+			lines := decodeLines(t, &buf)
+			if len(lines) != 2 {
+				t.Fatalf("expected handler and request lines, got %v", lines)
+			}
+			for _, line := range lines {
+				if line["request_id"] != id {
+					t.Errorf("expected request_id %q in %v", id, line)
+				}
+			}
+			if lines[1]["msg"] != "Request handled" || lines[1]["status"] != float64(http.StatusOK) {
+				t.Errorf("unexpected request line %v", lines[1])
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range []string{"", logger.FormatText, logger.FormatJSON} {
+		if _, err := logger.ParseFormat(format); err != nil {
+			t.Errorf("format %q: %v", format, err)
+		}
+	}
+	if _, err := logger.ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
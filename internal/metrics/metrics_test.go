@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/metrics"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStorage_RecordsLatencyAndErrors(t *testing.T) {
+	logger.Init()
+	registry := prometheus.NewRegistry()
+	storage := metrics.NewStorage(db.NewMemoryStorage(), metrics.New(registry))
+	ctx := context.Background()
+
+	if _, err := storage.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := storage.Get(ctx, "missing"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := storage.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{}}); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+
+	expected := `
+# HELP kv_storage_errors_total Failed storage operations by method and error reason.
+# TYPE kv_storage_errors_total counter
+kv_storage_errors_total{method="Create",reason="already_exists"} 1
+kv_storage_errors_total{method="Get",reason="not_found"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "kv_storage_errors_total"); err != nil {
+		t.Error(err)
+	}
+
+	// Длительность пишется для каждого вызова, в том числе неудачного
+	if n := testutil.CollectAndCount(registry, "kv_storage_operation_duration_seconds"); n != 2 {
+		t.Errorf("expected histograms for Create and Get, got %d", n)
+	}
+}
+
+func TestMiddleware_LabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+
+	r := gin.New()
+	r.Use(metrics.New(registry).Middleware())
+	r.GET("/kv/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	r.GET("/metrics", gin.WrapH(metrics.Handler(registry)))
+
+	for _, path := range []string{"/kv/a", "/kv/b", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, line := range []string{
+		`kv_http_requests_total{code="404",method="GET",route="/kv/:id"} 2`,
+		`kv_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`kv_http_request_duration_seconds_count{method="GET",route="/kv/:id"} 2`,
+		`kv_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected %q in metrics output:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// Storage измеряет длительность и ошибки каждого вызова обернутого хранилища
+type Storage struct {
+	db.Storage
+	m *Metrics
+}
+
+var _ db.Storage = (*Storage)(nil)
+
+// NewStorage оборачивает хранилище любой реализации
+func NewStorage(storage db.Storage, m *Metrics) *Storage {
+	return &Storage{Storage: storage, m: m}
+}
+
+func (s *Storage) Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.Create(ctx, in)
+	s.m.observe("Create", start, err)
+	return item, err
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.Get(ctx, key)
+	s.m.observe("Get", start, err)
+	return item, err
+}
+
+func (s *Storage) Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.Update(ctx, in, ifVersion)
+	s.m.observe("Update", start, err)
+	return item, err
+}
+
+func (s *Storage) Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.Delete(ctx, key, ifVersion)
+	s.m.observe("Delete", start, err)
+	return item, err
+}
+
+func (s *Storage) Put(ctx context.Context, in *models.KeyValue, mode db.PutMode, ifVersion uint64) (*models.KeyValue, bool, error) {
+	start := time.Now()
+	item, created, err := s.Storage.Put(ctx, in, mode, ifVersion)
+	s.m.observe("Put", start, err)
+	return item, created, err
+}
+
+func (s *Storage) List(ctx context.Context, opts db.ListOptions) ([]*models.KeyValue, string, error) {
+	start := time.Now()
+	items, cursor, err := s.Storage.List(ctx, opts)
+	s.m.observe("List", start, err)
+	return items, cursor, err
+}
+
+// Batch учитывает только ошибку всего пакета: ошибки отдельных
+// операций входят в ответ клиенту, а не в метрики хранилища
+func (s *Storage) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]db.BatchResult, error) {
+	start := time.Now()
+	results, err := s.Storage.Batch(ctx, ops, atomic)
+	s.m.observe("Batch", start, err)
+	return results, err
+}
+
+func (s *Storage) History(ctx context.Context, key string) ([]*models.KeyValue, error) {
+	start := time.Now()
+	items, err := s.Storage.History(ctx, key)
+	s.m.observe("History", start, err)
+	return items, err
+}
+
+func (s *Storage) GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.GetVersion(ctx, key, version)
+	s.m.observe("GetVersion", start, err)
+	return item, err
+}
+
+func (s *Storage) ListTrash(ctx context.Context, opts db.ListOptions) ([]*models.TrashedKeyValue, string, error) {
+	start := time.Now()
+	items, cursor, err := s.Storage.ListTrash(ctx, opts)
+	s.m.observe("ListTrash", start, err)
+	return items, cursor, err
+}
+
+func (s *Storage) RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error) {
+	start := time.Now()
+	item, err := s.Storage.RestoreTrash(ctx, key)
+	s.m.observe("RestoreTrash", start, err)
+	return item, err
+}
+
+func (s *Storage) Changes(ctx context.Context, opts db.ChangesOptions) ([]*models.Change, uint64, error) {
+	start := time.Now()
+	changes, revision, err := s.Storage.Changes(ctx, opts)
+	s.m.observe("Changes", start, err)
+	return changes, revision, err
+}
+
+// Namespaces считает операции всех пространств имен в общих метриках
+// Metrics: вызовы хранилищ пространств и создание, список и удаление
+// самих пространств. Имя пространства в метки не попадает, чтобы число
+// рядов не росло вместе с числом пространств.
+type Namespaces struct {
+	db.Namespaces
+	m *Metrics
+}
+
+var _ db.Namespaces = (*Namespaces)(nil)
+
+// NewNamespaces измеряет вызовы namespaces в метриках m
+func NewNamespaces(namespaces db.Namespaces, m *Metrics) *Namespaces {
+	return &Namespaces{Namespaces: namespaces, m: m}
+}
+
+func (n *Namespaces) Namespace(ctx context.Context, name string) (db.Storage, error) {
+	start := time.Now()
+	storage, err := n.Namespaces.Namespace(ctx, name)
+	n.m.observe("Namespace", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorage(storage, n.m), nil
+}
+
+func (n *Namespaces) CreateNamespace(ctx context.Context, name string) (*models.Namespace, error) {
+	start := time.Now()
+	ns, err := n.Namespaces.CreateNamespace(ctx, name)
+	n.m.observe("CreateNamespace", start, err)
+	return ns, err
+}
+
+func (n *Namespaces) ListNamespaces(ctx context.Context) ([]*models.Namespace, error) {
+	start := time.Now()
+	namespaces, err := n.Namespaces.ListNamespaces(ctx)
+	n.m.observe("ListNamespaces", start, err)
+	return namespaces, err
+}
+
+func (n *Namespaces) DeleteNamespace(ctx context.Context, name string) error {
+	start := time.Now()
+	err := n.Namespaces.DeleteNamespace(ctx, name)
+	n.m.observe("DeleteNamespace", start, err)
+	return err
+}
+
+// errorReason сводит ошибку к короткой метке с ограниченным набором значений
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, db.ErrBackendUnavailable):
+		return "unavailable"
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrVersionNotFound):
+		return "not_found"
+	case errors.Is(err, db.ErrNamespaceNotFound):
+		return "namespace_not_found"
+	case errors.Is(err, db.ErrAlreadyExists), errors.Is(err, db.ErrNamespaceExists):
+		return "already_exists"
+	case errors.Is(err, db.ErrVersionMismatch), errors.Is(err, db.ErrConflict):
+		return "conflict"
+	case errors.Is(err, db.ErrRevisionCompacted):
+		return "compacted"
+	case errors.Is(err, db.ErrInvalidCursor), errors.Is(err, db.ErrInvalidSelector),
+		errors.Is(err, db.ErrInvalidNamespace), errors.Is(err, db.ErrDefaultNamespace):
+		return "invalid"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Операции пакетного запроса
+const (
+	BatchGet    = "get"
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+type BatchRequest struct {
+	// Atomic включает режим "все или ничего": при ошибке любой операции
+	// изменения всех операций пакета откатываются
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchOperation struct {
+	Op    string                 `json:"op"`
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value,omitempty"`
+	// IfVersion - ожидаемая версия ключа для update и delete, 0 - без проверки
+	IfVersion uint64            `json:"if_version,omitempty"`
+	TTL       int64             `json:"ttl,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type BatchItemResult struct {
+	Status int       `json:"status"`
+	Result *KeyValue `json:"result,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Change - запись журнала изменений с глобальным номером ревизии
+type Change struct {
+	Revision uint64 `json:"revision"`
+	Event
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Типы событий изменения ключей
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// Event - изменение ключа. Для delete Value содержит удаленное значение.
+type Event struct {
+	Type    string                 `json:"type"`
+	Key     string                 `json:"key"`
+	Version uint64                 `json:"version,omitempty"`
+	Value   map[string]interface{} `json:"value,omitempty"`
+}
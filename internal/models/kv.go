@@ -1,6 +1,19 @@
 package models
 
+import "time"
+
 type KeyValue struct {
-	Key   string                 `json:"key"`
-	Value map[string]interface{} `json:"value"`
+	Key     string                 `json:"key"`
+	Value   map[string]interface{} `json:"value"`
+	Version uint64                 `json:"version,omitempty"`
+	// В запросе TTL задает время жизни ключа в секундах,
+	// в ответе - оставшееся время жизни
+	TTL       int64      `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// CreatedAt и UpdatedAt заполняет сервер, в запросе они игнорируются
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// Labels - произвольные метки ключа. При обновлении без меток
+	// сохраняются прежние, пустой объект удаляет все метки.
+	Labels map[string]string `json:"labels,omitempty"`
 }
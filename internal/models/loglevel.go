@@ -0,0 +1,7 @@
+package models
+
+// LogLevels - общий уровень логов и уровни, переопределенные для пакетов
+type LogLevels struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
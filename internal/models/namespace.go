@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// Namespace - пространство имен с собственным набором ключей
+type Namespace struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
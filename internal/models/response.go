@@ -1,8 +1,11 @@
 package models
 
 type Response struct {
-	Result  interface{} `json:"result,omitempty"`
-	Deleted interface{} `json:"deleted,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Message string      `json:"message,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Deleted    interface{} `json:"deleted,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	// Revision - ревизия журнала изменений, с которой продолжать чтение
+	Revision uint64 `json:"revision,omitempty"`
 }
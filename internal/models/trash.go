@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// TrashedKeyValue - мягко удаленный ключ в корзине
+type TrashedKeyValue struct {
+	KeyValue
+	DeletedAt time.Time `json:"deleted_at"`
+}
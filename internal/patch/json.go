@@ -0,0 +1,332 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchContentType - тип содержимого JSON Patch (RFC 6902)
+const JSONPatchContentType = "application/json-patch+json"
+
+var (
+	// ErrTestFailed возвращается, если операция test не совпала с документом
+	ErrTestFailed = errors.New("test operation failed")
+	// ErrInvalidPath возвращается, если путь операции не указывает на допустимое место в документе
+	ErrInvalidPath = errors.New("invalid patch path")
+)
+
+// Operation - одна операция JSON Patch
+type Operation struct {
+	Op    string
+	Path  []string
+	From  []string
+	Value interface{}
+}
+
+type rawOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ParseJSONPatch разбирает документ JSON Patch в список операций
+func ParseJSONPatch(rawPatch []byte) ([]Operation, error) {
+	var raws []rawOperation
+	if err := json.Unmarshal(rawPatch, &raws); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	ops := make([]Operation, 0, len(raws))
+	for i, raw := range raws {
+		op, err := parseOperation(raw)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func parseOperation(raw rawOperation) (Operation, error) {
+	op := Operation{Op: raw.Op}
+
+	if raw.Path == nil {
+		return op, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*raw.Path)
+	if err != nil {
+		return op, err
+	}
+	op.Path = path
+
+	switch raw.Op {
+	case "add", "replace", "test":
+		// "value": null допустимо, поэтому проверяется наличие поля, а не его значение
+		if raw.Value == nil {
+			return op, fmt.Errorf("%w: missing value for %s", ErrInvalidPatch, raw.Op)
+		}
+		if err := json.Unmarshal(raw.Value, &op.Value); err != nil {
+			return op, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+		}
+	case "move", "copy":
+		if raw.From == nil {
+			return op, fmt.Errorf("%w: missing from for %s", ErrInvalidPatch, raw.Op)
+		}
+		if op.From, err = parsePointer(*raw.From); err != nil {
+			return op, err
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, raw.Op)
+	}
+	return op, nil
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901) на токены
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// ApplyJSONPatch последовательно применяет операции к документу target.
+// При ошибке любой операции документ не должен сохраняться: он мог быть
+// изменен частично.
+func ApplyJSONPatch(target map[string]interface{}, ops []Operation) (map[string]interface{}, error) {
+	var doc interface{} = target
+	for i, op := range ops {
+		var err error
+		if doc, err = applyOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	result, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, ErrNotObject
+	}
+	return result, nil
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add":
+		return addValue(doc, op.Path, op.Value)
+	case "remove":
+		doc, _, err := removeValue(doc, op.Path)
+		return doc, err
+	case "replace":
+		if len(op.Path) == 0 {
+			return op.Value, nil
+		}
+		doc, _, err := removeValue(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, op.Path, op.Value)
+	case "move":
+		if isProperPrefix(op.From, op.Path) {
+			return nil, fmt.Errorf("%w: cannot move a value into one of its children", ErrInvalidPath)
+		}
+		doc, value, err := removeValue(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, op.Path, value)
+	case "copy":
+		value, err := getValue(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, op.Path, deepCopy(value))
+	case "test":
+		value, err := getValue(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func getValue(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrInvalidPath, token)
+			}
+			doc = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("%w: %q is not a container", ErrInvalidPath, token)
+		}
+	}
+	return doc, nil
+}
+
+// addValue добавляет value по пути path и возвращает новый корень документа.
+// Корень может измениться, если путь пустой или вставка расширила массив.
+func addValue(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q not found", ErrInvalidPath, token)
+		}
+		newChild, err := addValue(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = newChild
+		return node, nil
+	case []interface{}:
+		if len(rest) == 0 {
+			if token == "-" {
+				return append(node, value), nil
+			}
+			idx, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[idx+1:], node[idx:])
+			node[idx] = value
+			return node, nil
+		}
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := addValue(node[idx], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = newChild
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w: %q is not a container", ErrInvalidPath, token)
+	}
+}
+
+// removeValue удаляет значение по пути path и возвращает новый корень
+// документа и удаленное значение
+func removeValue(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPath)
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q not found", ErrInvalidPath, token)
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+		newChild, removed, err := removeValue(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = newChild
+		return node, removed, nil
+	case []interface{}:
+		idx, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[idx]
+			return append(node[:idx], node[idx+1:]...), removed, nil
+		}
+		newChild, removed, err := removeValue(node[idx], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[idx] = newChild
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q is not a container", ErrInvalidPath, token)
+	}
+}
+
+// arrayIndex разбирает индекс массива в диапазоне [0, maxIndex].
+// Ведущие нули и знаки запрещены RFC 6901.
+func arrayIndex(token string, maxIndex int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPath, token)
+	}
+
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx > maxIndex {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrInvalidPath, token)
+	}
+	return idx, nil
+}
+
+func isProperPrefix(prefix, path []string) bool {
+	if len(prefix) >= len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[key] = deepCopy(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = deepCopy(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,88 @@
+package patch_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/patch"
+)
+
+func applyJSONPatch(t *testing.T, target, rawPatch string) (map[string]interface{}, error) {
+	t.Helper()
+	ops, err := patch.ParseJSONPatch([]byte(rawPatch))
+	if err != nil {
+		return nil, err
+	}
+	return patch.ApplyJSONPatch(decode(t, target), ops)
+}
+
+// Примеры из приложения A RFC 6902
+func TestApplyJSONPatch_RFCExamples(t *testing.T) {
+	cases := []struct {
+		name, target, patch, expected string
+	}{
+		{"add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{
+			"move value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{
+			"test value success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{"add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"escape ordering", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`},
+		{"replace root", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+		{"copy value", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"foo":{"bar":1},"baz":{"bar":1}}`},
+	}
+
+	for _, tc := range cases {
+		got, err := applyJSONPatch(t, tc.target, tc.patch)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if expected := decode(t, tc.expected); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, expected, got)
+		}
+	}
+}
+
+func TestApplyJSONPatch_Errors(t *testing.T) {
+	cases := []struct {
+		name, target, patch string
+		expected            error
+	}{
+		{"test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, patch.ErrTestFailed},
+		{"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, patch.ErrInvalidPath},
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, patch.ErrInvalidPath},
+		{"array index out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":"qux"}]`, patch.ErrInvalidPath},
+		{"leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, patch.ErrInvalidPath},
+		{"move into child", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, patch.ErrInvalidPath},
+		{"pointer without slash", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, patch.ErrInvalidPath},
+		{"unknown operation", `{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`, patch.ErrInvalidPatch},
+		{"missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, patch.ErrInvalidPatch},
+		{"not an array", `{"foo":"bar"}`, `{"op":"add","path":"/baz","value":1}`, patch.ErrInvalidPatch},
+		{"replace root with array", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, patch.ErrNotObject},
+		{"add root array", `{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, patch.ErrNotObject},
+	}
+
+	for _, tc := range cases {
+		_, err := applyJSONPatch(t, tc.target, tc.patch)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MergePatchContentType - тип содержимого JSON Merge Patch (RFC 7396)
+const MergePatchContentType = "application/merge-patch+json"
+
+// ErrInvalidPatch возвращается, если тело патча не удалось разобрать
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// ErrNotObject возвращается, если после применения патча значение
+// перестало быть JSON-объектом и не может быть сохранено
+var ErrNotObject = errors.New("patched value must be a JSON object")
+
+// MergePatch применяет JSON Merge Patch (RFC 7396) к документу target.
+// target изменяется на месте и возвращается как результат.
+func MergePatch(target map[string]interface{}, rawPatch []byte) (map[string]interface{}, error) {
+	p, err := ParseMergePatch(rawPatch)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyMergePatch(target, p)
+}
+
+// ParseMergePatch разбирает документ JSON Merge Patch
+func ParseMergePatch(rawPatch []byte) (interface{}, error) {
+	var p interface{}
+	if err := json.Unmarshal(rawPatch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return p, nil
+}
+
+// ApplyMergePatch применяет разобранный ParseMergePatch документ к target.
+// target изменяется на месте и возвращается как результат.
+func ApplyMergePatch(target map[string]interface{}, p interface{}) (map[string]interface{}, error) {
+	merged, ok := mergeValue(target, p).(map[string]interface{})
+	if !ok {
+		return nil, ErrNotObject
+	}
+	return merged, nil
+}
+
+// mergeValue реализует алгоритм MergePatch из раздела 2 RFC 7396
+func mergeValue(target, p interface{}) interface{} {
+	patchObj, ok := p.(map[string]interface{})
+	if !ok {
+		return p
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok || targetObj == nil {
+		targetObj = make(map[string]interface{}, len(patchObj))
+	}
+
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergeValue(targetObj[name], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,56 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/patch"
+)
+
+func decode(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return doc
+}
+
+// Примеры из приложения A RFC 7396, в которых документ остается объектом
+func TestMergePatch_RFCExamples(t *testing.T) {
+	cases := []struct {
+		target, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range cases {
+		got, err := patch.MergePatch(decode(t, tc.target), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error %v", tc.target, tc.patch, err)
+			continue
+		}
+		if expected := decode(t, tc.expected); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s + %s: expected %v, got %v", tc.target, tc.patch, expected, got)
+		}
+	}
+}
+
+func TestMergePatch_NotObject(t *testing.T) {
+	for _, p := range []string{`["c"]`, `"bar"`, `null`} {
+		if _, err := patch.MergePatch(decode(t, `{"a":"b"}`), []byte(p)); !errors.Is(err, patch.ErrNotObject) {
+			t.Errorf("patch %s: expected ErrNotObject, got %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Атрибуты спанов хранилища
+const (
+	namespaceKey   = attribute.Key("kv.namespace")
+	keyKey         = attribute.Key("kv.key")
+	prefixKey      = attribute.Key("kv.prefix")
+	batchSizeKey   = attribute.Key("kv.batch.size")
+	batchAtomicKey = attribute.Key("kv.batch.atomic")
+)
+
+// Storage создает дочерний спан на каждый вызов обернутого хранилища
+// с ключом и пространством имен. Реализация хранилища может добавить
+// к нему свои спаны, например KeyValueManager - спан вызова Lua-функции.
+type Storage struct {
+	db.Storage
+	namespace string
+}
+
+var _ db.Storage = (*Storage)(nil)
+
+// NewStorage оборачивает хранилище пространства имен namespace
+func NewStorage(storage db.Storage, namespace string) *Storage {
+	return &Storage{Storage: storage, namespace: namespace}
+}
+
+func (s *Storage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, namespaceKey.String(s.namespace))
+	return tracer.Start(ctx, "Storage."+method, trace.WithAttributes(attrs...))
+}
+
+func (s *Storage) Create(ctx context.Context, in *models.KeyValue) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "Create", keyKey.String(in.Key))
+	item, err := s.Storage.Create(ctx, in)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) Get(ctx context.Context, key string) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "Get", keyKey.String(key))
+	item, err := s.Storage.Get(ctx, key)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) Update(ctx context.Context, in *models.KeyValue, ifVersion uint64) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "Update", keyKey.String(in.Key))
+	item, err := s.Storage.Update(ctx, in, ifVersion)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) Delete(ctx context.Context, key string, ifVersion uint64) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "Delete", keyKey.String(key))
+	item, err := s.Storage.Delete(ctx, key, ifVersion)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) Put(ctx context.Context, in *models.KeyValue, mode db.PutMode, ifVersion uint64) (*models.KeyValue, bool, error) {
+	ctx, span := s.start(ctx, "Put", keyKey.String(in.Key))
+	item, created, err := s.Storage.Put(ctx, in, mode, ifVersion)
+	end(span, err)
+	return item, created, err
+}
+
+func (s *Storage) List(ctx context.Context, opts db.ListOptions) ([]*models.KeyValue, string, error) {
+	ctx, span := s.start(ctx, "List", prefixKey.String(opts.Prefix))
+	items, cursor, err := s.Storage.List(ctx, opts)
+	end(span, err)
+	return items, cursor, err
+}
+
+func (s *Storage) Batch(ctx context.Context, ops []models.BatchOperation, atomic bool) ([]db.BatchResult, error) {
+	ctx, span := s.start(ctx, "Batch", batchSizeKey.Int(len(ops)), batchAtomicKey.Bool(atomic))
+	results, err := s.Storage.Batch(ctx, ops, atomic)
+	end(span, err)
+	return results, err
+}
+
+func (s *Storage) History(ctx context.Context, key string) ([]*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "History", keyKey.String(key))
+	items, err := s.Storage.History(ctx, key)
+	end(span, err)
+	return items, err
+}
+
+func (s *Storage) GetVersion(ctx context.Context, key string, version uint64) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "GetVersion", keyKey.String(key))
+	item, err := s.Storage.GetVersion(ctx, key, version)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) ListTrash(ctx context.Context, opts db.ListOptions) ([]*models.TrashedKeyValue, string, error) {
+	ctx, span := s.start(ctx, "ListTrash", prefixKey.String(opts.Prefix))
+	items, cursor, err := s.Storage.ListTrash(ctx, opts)
+	end(span, err)
+	return items, cursor, err
+}
+
+func (s *Storage) RestoreTrash(ctx context.Context, key string) (*models.KeyValue, error) {
+	ctx, span := s.start(ctx, "RestoreTrash", keyKey.String(key))
+	item, err := s.Storage.RestoreTrash(ctx, key)
+	end(span, err)
+	return item, err
+}
+
+func (s *Storage) Changes(ctx context.Context, opts db.ChangesOptions) ([]*models.Change, uint64, error) {
+	ctx, span := s.start(ctx, "Changes")
+	changes, revision, err := s.Storage.Changes(ctx, opts)
+	end(span, err)
+	return changes, revision, err
+}
+
+// Namespaces создает спаны вызовов хранилищ пространств имен с атрибутом
+// kv.namespace, а также спан поиска пространства в Namespace. Создание,
+// список и удаление пространств отдельных спанов не получают: их видно
+// по спану HTTP-запроса и спану Lua-функции.
+type Namespaces struct {
+	db.Namespaces
+}
+
+var _ db.Namespaces = (*Namespaces)(nil)
+
+// NewNamespaces включает спаны вызовов для хранилищ из namespaces
+func NewNamespaces(namespaces db.Namespaces) *Namespaces {
+	return &Namespaces{Namespaces: namespaces}
+}
+
+func (n *Namespaces) Namespace(ctx context.Context, name string) (db.Storage, error) {
+	ctx, span := tracer.Start(ctx, "Namespaces.Namespace", trace.WithAttributes(namespaceKey.String(name)))
+	storage, err := n.Namespaces.Namespace(ctx, name)
+	end(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorage(storage, name), nil
+}
+
+// end завершает спан вызова. Отсутствие ключа или пространства имен -
+// обычный ответ хранилища, поэтому такие спаны не помечаются ошибкой.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, db.ErrNotFound) && !errors.Is(err, db.ErrVersionNotFound) && !errors.Is(err, db.ErrNamespaceNotFound) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// instrumentationName - имя, под которым сервис создает свои спаны
+const instrumentationName = "github.com/MosinFAM/tarantool-kv"
+
+// DefaultServiceName - имя сервиса в спанах, если не задан OTEL_SERVICE_NAME
+const DefaultServiceName = "kv-server"
+
+// Экспортеры спанов
+const (
+	// ExporterNone отключает запись спанов, но оставляет передачу traceparent
+	ExporterNone = "none"
+	// ExporterStdout пишет спаны в stdout в JSON
+	ExporterStdout = "stdout"
+	// ExporterFile пишет спаны в файл в JSON, по одному объекту на спан
+	ExporterFile = "file"
+	// ExporterOTLP отправляет спаны по OTLP/HTTP, адрес задается
+	// стандартными переменными OTEL_EXPORTER_OTLP_*
+	ExporterOTLP = "otlp"
+)
+
+var tracer = otel.Tracer(instrumentationName)
+
+// Config - настройки трассировки
+type Config struct {
+	// Exporter - один из Exporter*, пустое значение равно ExporterNone
+	Exporter string
+	// File - путь файла для ExporterFile
+	File string
+}
+
+// Setup устанавливает глобальный провайдер спанов и распространение
+// контекста в формате W3C Trace Context. Возвращаемая функция выгружает
+// накопленные спаны и закрывает экспортер.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var (
+		provider *sdktrace.TracerProvider
+		closer   io.Closer
+	)
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(DefaultServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	// Имя из OTEL_SERVICE_NAME важнее имени по умолчанию
+	if res, err = resource.Merge(res, resource.Environment()); err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	switch cfg.Exporter {
+	case ExporterStdout, ExporterFile:
+		out := io.Writer(os.Stdout)
+		if cfg.Exporter == ExporterFile {
+			if cfg.File == "" {
+				return nil, errors.New("trace file path is required for the file exporter")
+			}
+			f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open trace file: %w", err)
+			}
+			out, closer = f, f
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		// Спаны пишутся сразу, чтобы файл был полным и без корректной остановки сервера
+		provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithResource(res))
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,130 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exporter собирает спаны всех тестов пакета. Трейсеры пакета tracing
+// привязываются к первому установленному провайдеру, поэтому он один.
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+func spanByName(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func hasAttribute(span *tracetest.SpanStub, want attribute.KeyValue) bool {
+	for _, attr := range span.Attributes {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMiddleware_ContinuesTraceAndParentsStorageSpans(t *testing.T) {
+	exporter.Reset()
+	logger.Init()
+	gin.SetMode(gin.TestMode)
+
+	storage := tracing.NewStorage(db.NewMemoryStorage(), db.DefaultNamespace)
+	r := gin.New()
+	r.Use(tracing.Middleware())
+	r.GET("/kv/:id", func(c *gin.Context) {
+		if _, err := storage.Get(c.Request.Context(), c.Param("id")); err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/kv/missing", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	server := spanByName(spans, "GET /kv/:id")
+	if server == nil {
+		t.Fatalf("expected server span, got %v", spans)
+	}
+	if server.SpanKind != trace.SpanKindServer || server.SpanContext.TraceID().String() != traceID {
+		t.Errorf("expected server span in trace %s, got kind %v trace %s", traceID, server.SpanKind, server.SpanContext.TraceID())
+	}
+	if !hasAttribute(server, attribute.Int("http.response.status_code", http.StatusNotFound)) {
+		t.Errorf("expected status code attribute, got %v", server.Attributes)
+	}
+
+	get := spanByName(spans, "Storage.Get")
+	if get == nil {
+		t.Fatalf("expected storage span, got %v", spans)
+	}
+	if get.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("expected storage span to be a child of the server span")
+	}
+	if !hasAttribute(get, attribute.String("kv.key", "missing")) {
+		t.Errorf("expected key attribute, got %v", get.Attributes)
+	}
+	// Отсутствие ключа - не ошибка хранилища, но событие записывается
+	if len(get.Events) != 1 || get.Status.Code.String() == "Error" {
+		t.Errorf("expected not-found to be recorded without error status, got %v %v", get.Events, get.Status)
+	}
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterFile, File: path})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "offline")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"Name":"offline"`) {
+		t.Errorf("expected span in trace file, got %s", raw)
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.Config{Exporter: "jaeger"}); err == nil {
+		t.Error("expected error for unknown exporter")
+	}
+	if _, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterFile}); err == nil {
+		t.Error("expected error for file exporter without path")
+	}
+}
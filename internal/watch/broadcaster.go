@@ -0,0 +1,149 @@
+package watch
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+)
+
+// DefaultBuffer - число событий, которые подписчик может не успеть прочитать
+// до того, как будет отключен
+const DefaultBuffer = 64
+
+var (
+	// ErrOverflow возвращается подписке, которая не успевала читать события
+	ErrOverflow = errors.New("subscriber buffer overflow")
+	// ErrClosed возвращается подпискам закрытой рассылки
+	ErrClosed = errors.New("broadcaster closed")
+	// ErrShutdown возвращается подпискам при остановке сервера
+	ErrShutdown = errors.New("server is shutting down")
+)
+
+// Broadcaster рассылает события изменения ключей подписчикам.
+// Publish никогда не блокируется: подписчик с переполненным буфером
+// отключается, и его канал событий закрывается.
+type Broadcaster struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	buffer int
+	// err - причина остановки рассылки, после Shutdown новые
+	// подписки сразу закрываются с этой ошибкой
+	err error
+}
+
+func NewBroadcaster(buffer int) *Broadcaster {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broadcaster{subs: make(map[*Subscription]struct{}), buffer: buffer}
+}
+
+// Subscription - подписка на события ключей с заданным префиксом
+type Subscription struct {
+	b      *Broadcaster
+	prefix string
+	events chan models.Event
+	err    error
+}
+
+// Subscribe создает подписку на события ключей с префиксом prefix.
+// Подписку нужно закрыть через Close.
+func (b *Broadcaster) Subscribe(prefix string) *Subscription {
+	s := &Subscription{b: b, prefix: prefix, events: make(chan models.Event, b.buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		s.err = b.err
+		close(s.events)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Publish отправляет событие всем подписчикам, префикс которых подходит к ключу
+func (b *Broadcaster) Publish(event models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subs {
+		if !strings.HasPrefix(event.Key, s.prefix) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.err = ErrOverflow
+			b.removeLocked(s)
+		}
+	}
+}
+
+// Close отключает всех подписчиков с ошибкой ErrClosed, например при
+// удалении пространства имен. Новые подписки после Close не ограничены.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked(ErrClosed)
+}
+
+// Shutdown отключает всех подписчиков с ошибкой ErrShutdown и закрывает
+// так же все последующие подписки, чтобы потоки watch не задерживали
+// остановку сервера
+func (b *Broadcaster) Shutdown() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = ErrShutdown
+	b.closeLocked(ErrShutdown)
+}
+
+// drop отключает всех текущих подписчиков с ошибкой err
+func (b *Broadcaster) drop(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked(err)
+}
+
+func (b *Broadcaster) closeLocked(err error) {
+	for s := range b.subs {
+		s.err = err
+		b.removeLocked(s)
+	}
+}
+
+// Len возвращает число активных подписок
+func (b *Broadcaster) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broadcaster) removeLocked(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// Events возвращает канал событий. Канал закрывается при Close
+// или при отключении медленного подписчика, см. Err.
+func (s *Subscription) Events() <-chan models.Event {
+	return s.events
+}
+
+// Err возвращает причину отключения подписки после закрытия канала событий
+func (s *Subscription) Err() error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.err
+}
+
+// Close отменяет подписку. Повторный вызов ничего не делает.
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.removeLocked(s)
+}
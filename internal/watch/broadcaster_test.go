@@ -0,0 +1,214 @@
+package watch_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+	"github.com/MosinFAM/tarantool-kv/internal/models"
+	"github.com/MosinFAM/tarantool-kv/internal/watch"
+)
+
+func TestBroadcaster_PrefixFilter(t *testing.T) {
+	b := watch.NewBroadcaster(4)
+	sub := b.Subscribe("app/")
+	defer sub.Close()
+
+	b.Publish(models.Event{Type: models.EventCreate, Key: "other"})
+	b.Publish(models.Event{Type: models.EventCreate, Key: "app/a"})
+
+	event := <-sub.Events()
+	if event.Key != "app/a" {
+		t.Errorf("expected event for app/a, got %+v", event)
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestBroadcaster_SlowSubscriberDropped(t *testing.T) {
+	b := watch.NewBroadcaster(1)
+	sub := b.Subscribe("")
+
+	b.Publish(models.Event{Type: models.EventCreate, Key: "a"})
+	b.Publish(models.Event{Type: models.EventUpdate, Key: "a"})
+
+	if b.Len() != 0 {
+		t.Errorf("expected slow subscriber to be removed, got %d subscribers", b.Len())
+	}
+
+	<-sub.Events()
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected events channel to be closed")
+	}
+	if !errors.Is(sub.Err(), watch.ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", sub.Err())
+	}
+	sub.Close()
+}
+
+// nextEvent ждет следующего события подписки, которое публикует
+// чтение журнала изменений
+func nextEvent(t *testing.T, sub *watch.Subscription) models.Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription closed: %v", sub.Err())
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return models.Event{}
+}
+
+func TestNamespaces_PublishesChangelog(t *testing.T) {
+	logger.Init()
+	ctx := context.Background()
+	source := db.NewMemoryNamespaces(db.WithExpireInterval(10 * time.Millisecond))
+	n := watch.NewNamespaces(source, 8, watch.WithPollInterval(5*time.Millisecond))
+	sub := n.Broadcaster(db.DefaultNamespace).Subscribe("")
+	defer sub.Close()
+
+	s, err := n.Namespace(ctx, db.DefaultNamespace)
+	if err != nil {
+		t.Fatalf("open namespace: %v", err)
+	}
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 1.0}}); err == nil {
+		t.Fatal("expected duplicate create to fail")
+	}
+	if _, _, err := s.Put(ctx, &models.KeyValue{Key: "a", Value: map[string]interface{}{"n": 2.0}}, db.PutUpsert, 0); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, err := s.Delete(ctx, "a", 0); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	// Истекший ключ удаляет фоновая очистка, а не запрос через API
+	expired := time.Now().Add(-time.Second)
+	if _, err := s.Create(ctx, &models.KeyValue{Key: "session", Value: map[string]interface{}{"n": 1.0}, ExpiresAt: &expired}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, want := range []models.Event{
+		{Type: models.EventCreate, Key: "a", Version: 1},
+		{Type: models.EventUpdate, Key: "a", Version: 2},
+		{Type: models.EventDelete, Key: "a", Version: 2},
+		{Type: models.EventCreate, Key: "session", Version: 1},
+		{Type: models.EventDelete, Key: "session", Version: 1},
+	} {
+		got := nextEvent(t, sub)
+		if got.Type != want.Type || got.Key != want.Key || got.Version != want.Version {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestNamespaces_ConcurrentWritesKeepVersionOrder(t *testing.T) {
+	logger.Init()
+	ctx := context.Background()
+	n := watch.NewNamespaces(db.NewMemoryNamespaces(), 64, watch.WithPollInterval(5*time.Millisecond))
+	sub := n.Broadcaster(db.DefaultNamespace).Subscribe("")
+	defer sub.Close()
+
+	s, err := n.Namespace(ctx, db.DefaultNamespace)
+	if err != nil {
+		t.Fatalf("open namespace: %v", err)
+	}
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value := map[string]interface{}{"writer": float64(i)}
+			if _, _, err := s.Put(ctx, &models.KeyValue{Key: "counter", Value: value}, db.PutUpsert, 0); err != nil {
+				t.Errorf("put: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for version := uint64(1); version <= writers; version++ {
+		if event := nextEvent(t, sub); event.Version != version {
+			t.Fatalf("expected version %d, got %+v", version, event)
+		}
+	}
+}
+
+func TestNamespaces_EventsAreScopedAndClosedOnDelete(t *testing.T) {
+	logger.Init()
+	ctx := context.Background()
+	n := watch.NewNamespaces(db.NewMemoryNamespaces(), 4)
+	if _, err := n.CreateNamespace(ctx, "team-a"); err != nil {
+		t.Fatalf("create namespace: %v", err)
+	}
+
+	sub := n.Broadcaster("team-a").Subscribe("")
+	defer sub.Close()
+
+	defaultStorage, err := n.Namespace(ctx, db.DefaultNamespace)
+	if err != nil {
+		t.Fatalf("open default namespace: %v", err)
+	}
+	teamA, err := n.Namespace(ctx, "team-a")
+	if err != nil {
+		t.Fatalf("open namespace: %v", err)
+	}
+	value := map[string]interface{}{"n": 1.0}
+	if _, err := defaultStorage.Create(ctx, &models.KeyValue{Key: "default-key", Value: value}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := teamA.Create(ctx, &models.KeyValue{Key: "team-key", Value: value}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if event := <-sub.Events(); event.Key != "team-key" {
+		t.Errorf("expected event for team-key, got %+v", event)
+	}
+
+	if err := n.DeleteNamespace(ctx, "team-a"); err != nil {
+		t.Fatalf("delete namespace: %v", err)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("expected events channel to be closed")
+	}
+	if !errors.Is(sub.Err(), watch.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", sub.Err())
+	}
+}
+
+func TestNamespaces_Shutdown(t *testing.T) {
+	logger.Init()
+	n := watch.NewNamespaces(db.NewMemoryNamespaces(), 4)
+	sub := n.Broadcaster(db.DefaultNamespace).Subscribe("")
+
+	n.Shutdown()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("expected events channel to be closed")
+	}
+	if !errors.Is(sub.Err(), watch.ErrShutdown) {
+		t.Errorf("expected ErrShutdown, got %v", sub.Err())
+	}
+
+	// Подписки, открытые после остановки, закрываются сразу
+	for _, name := range []string{db.DefaultNamespace, "team-a"} {
+		late := n.Broadcaster(name).Subscribe("")
+		if _, ok := <-late.Events(); ok {
+			t.Errorf("%s: expected late subscription to be closed", name)
+		}
+		if !errors.Is(late.Err(), watch.ErrShutdown) {
+			t.Errorf("%s: expected ErrShutdown, got %v", name, late.Err())
+		}
+	}
+}
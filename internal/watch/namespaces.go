@@ -0,0 +1,240 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MosinFAM/tarantool-kv/internal/db"
+	"github.com/MosinFAM/tarantool-kv/internal/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logger.Package("watch")
+
+// DefaultPollInterval - период чтения новых записей журнала изменений
+const DefaultPollInterval = 100 * time.Millisecond
+
+// pollLimit - число записей журнала, читаемых за один запрос
+const pollLimit = 1000
+
+// headTimeout ограничивает запрос текущей ревизии журнала при создании рассылки
+const headTimeout = 5 * time.Second
+
+// Namespaces ведет для каждого пространства имен рассылку, которую
+// наполняет журнал изменений пространства. Поэтому подписчики получают
+// и записи, сделанные не через этот процесс: удаления истекших ключей,
+// очистку корзины, запросы к другим экземплярам сервера с тем же Tarantool.
+// События публикуются в порядке ревизий журнала.
+type Namespaces struct {
+	db.Namespaces
+	source   db.Namespaces
+	buffer   int
+	interval time.Duration
+
+	mu       sync.Mutex
+	feeds    map[string]*feed
+	shutdown bool
+}
+
+var _ db.Namespaces = (*Namespaces)(nil)
+
+// feed - рассылка пространства имен и чтение его журнала
+type feed struct {
+	b      *Broadcaster
+	cancel context.CancelFunc
+}
+
+// Option настраивает Namespaces
+type Option func(*Namespaces)
+
+// WithPollInterval задает период чтения журнала изменений
+func WithPollInterval(interval time.Duration) Option {
+	return func(n *Namespaces) {
+		n.interval = interval
+	}
+}
+
+// WithSource задает пространства имен, журнал которых читают рассылки.
+// По умолчанию это обернутые пространства; отдельный источник позволяет
+// не учитывать опрос журнала в метриках и трейсах.
+func WithSource(source db.Namespaces) Option {
+	return func(n *Namespaces) {
+		n.source = source
+	}
+}
+
+// NewNamespaces оборачивает пространства имен. buffer задает размер
+// буфера подписчика, как в NewBroadcaster.
+func NewNamespaces(namespaces db.Namespaces, buffer int, opts ...Option) *Namespaces {
+	n := &Namespaces{
+		Namespaces: namespaces,
+		source:     namespaces,
+		buffer:     buffer,
+		interval:   DefaultPollInterval,
+		feeds:      make(map[string]*feed),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// DeleteNamespace удаляет пространство и отключает его подписчиков
+func (n *Namespaces) DeleteNamespace(ctx context.Context, name string) error {
+	if err := n.Namespaces.DeleteNamespace(ctx, name); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	f, ok := n.feeds[name]
+	delete(n.feeds, name)
+	n.mu.Unlock()
+
+	if ok {
+		f.cancel()
+		f.b.Close()
+	}
+	return nil
+}
+
+// Shutdown останавливает рассылки всех пространств имен, см. Broadcaster.Shutdown.
+// Подходит для http.Server.RegisterOnShutdown.
+func (n *Namespaces) Shutdown() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.shutdown = true
+	for _, f := range n.feeds {
+		f.cancel()
+		f.b.Shutdown()
+	}
+}
+
+// Broadcaster возвращает рассылку пространства имен, создавая ее при первом
+// обращении. Журнал читается с ревизии, текущей на момент создания, поэтому
+// подписчик получает все изменения после Subscribe.
+func (n *Namespaces) Broadcaster(name string) *Broadcaster {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if f, ok := n.feeds[name]; ok {
+		return f.b
+	}
+
+	b := NewBroadcaster(n.buffer)
+	if n.shutdown {
+		b.Shutdown()
+		n.feeds[name] = &feed{b: b, cancel: func() {}}
+		return b
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &feed{b: b, cancel: cancel}
+	n.feeds[name] = f
+
+	r := &reader{name: name, source: n.source, b: b}
+	headCtx, headCancel := context.WithTimeout(ctx, headTimeout)
+	r.sync(headCtx)
+	headCancel()
+	go r.run(ctx, n.interval)
+	return b
+}
+
+// reader переносит записи журнала пространства имен в рассылку
+type reader struct {
+	name    string
+	source  db.Namespaces
+	b       *Broadcaster
+	storage db.Storage
+	// since - последняя опубликованная ревизия, synced - известна ли она
+	since  uint64
+	synced bool
+	// failed - завершился ли ошибкой предыдущий запрос, чтобы не писать
+	// в лог одну и ту же ошибку на каждом опросе
+	failed bool
+}
+
+func (r *reader) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !r.synced {
+			r.sync(ctx)
+			continue
+		}
+		r.poll(ctx)
+	}
+}
+
+// sync запоминает текущую ревизию журнала, с которой начнется чтение
+func (r *reader) sync(ctx context.Context) {
+	storage, err := r.open(ctx)
+	if err != nil {
+		r.fail(ctx, err)
+		return
+	}
+	_, head, err := storage.Changes(ctx, db.ChangesOptions{FromHead: true})
+	if err != nil {
+		r.fail(ctx, err)
+		return
+	}
+	r.since, r.synced, r.failed = head, true, false
+}
+
+// poll публикует записи журнала после since
+func (r *reader) poll(ctx context.Context) {
+	storage, err := r.open(ctx)
+	if err != nil {
+		r.fail(ctx, err)
+		return
+	}
+	for {
+		changes, next, err := storage.Changes(ctx, db.ChangesOptions{Since: r.since, Limit: pollLimit})
+		if errors.Is(err, db.ErrRevisionCompacted) {
+			// Пропущенные события уже не прочитать: подписчики отключаются,
+			// как при переполнении буфера, и должны перечитать ключи
+			log.LogInfo(ctx, "Watch feed fell behind changelog compaction", logrus.Fields{"namespace": r.name, "since": r.since})
+			r.b.drop(ErrOverflow)
+			r.synced = false
+			return
+		}
+		if err != nil {
+			r.fail(ctx, err)
+			return
+		}
+		r.failed = false
+		for _, change := range changes {
+			r.b.Publish(change.Event)
+		}
+		r.since = next
+		if len(changes) < pollLimit {
+			return
+		}
+	}
+}
+
+func (r *reader) open(ctx context.Context) (db.Storage, error) {
+	if r.storage == nil {
+		storage, err := r.source.Namespace(ctx, r.name)
+		if err != nil {
+			return nil, err
+		}
+		r.storage = storage
+	}
+	return r.storage, nil
+}
+
+func (r *reader) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil || r.failed {
+		return
+	}
+	r.failed = true
+	log.LogError(ctx, "Failed to read changelog for watch", err, logrus.Fields{"namespace": r.name})
+}